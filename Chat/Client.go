@@ -0,0 +1,131 @@
+package Chat
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Time allowed to write a message to the peer
+	writeWait = 10 * time.Second
+
+	// Time allowed to read the next pong message from the peer
+	pongWait = 60 * time.Second
+
+	// Pings are sent with this period, which must be less than pongWait
+	pingPeriod = (pongWait * 9) / 10
+
+	// Largest message accepted from a peer
+	maxMessageSize = 4096
+
+	// Messages queued per client before it counts as a slow consumer
+	sendQueueSize = 256
+)
+
+// Client is one WebSocket connection in a room. Only the write pump writes to
+// the connection, everything else goes through the buffered send queue.
+type Client struct {
+	room *Room
+	conn *websocket.Conn
+	send chan []byte
+}
+
+func newClient(room *Room, conn *websocket.Conn) *Client {
+	return &Client{room: room, conn: conn, send: make(chan []byte, sendQueueSize)}
+}
+
+// enqueue queues data without blocking and reports whether there was room.
+// Callers must hold the room lock so the queue cannot be closed concurrently.
+func (c *Client) enqueue(data []byte) bool {
+	select {
+	case c.send <- data:
+		return true
+	default:
+		return false
+	}
+}
+
+// Send queues message for this client only, e.g. an error about something it
+// sent. It is dropped when the client has left or its queue is full.
+func (c *Client) Send(message interface{}) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		slog.Error("Could not encode chat message", "room", c.room.name, "error", err)
+		return
+	}
+
+	c.room.mu.RLock()
+	defer c.room.mu.RUnlock()
+
+	if _, joined := c.room.clients[c]; joined {
+		c.enqueue(data)
+	}
+}
+
+// Close makes the client leave its room. Messages already queued, e.g. an
+// error sent just before, are written before the connection is closed.
+func (c *Client) Close() {
+	c.room.leave(c)
+}
+
+// ReadLoop reads messages until the connection fails or the peer stops
+// answering pings, passing each one to handle. The client leaves the room
+// when it returns.
+func (c *Client) ReadLoop(handle func(data []byte)) {
+	defer c.room.hub.readers.Done()
+	defer c.room.leave(c)
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				slog.Warn("Error reading chat message", "room", c.room.name, "error", err)
+			}
+			return
+		}
+		handle(data)
+	}
+}
+
+// writePump drains the send queue and pings the peer. It closes the
+// connection when the queue is closed or a write fails.
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case data, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				// The room closed the queue
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				slog.Warn("Error writing to chat client", "room", c.room.name, "error", err)
+				c.room.leave(c)
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.room.leave(c)
+				return
+			}
+		}
+	}
+}
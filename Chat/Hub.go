@@ -0,0 +1,173 @@
+package Chat
+
+import (
+	"backend/Metrics"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// Hub keeps track of the live rooms. Rooms have their own locks, the hub lock
+// only guards the map of rooms.
+type Hub struct {
+	mu    sync.Mutex
+	rooms map[string]*Room
+	// Read loops still running, Shutdown waits for them
+	readers sync.WaitGroup
+}
+
+func NewHub() *Hub {
+	return &Hub{rooms: make(map[string]*Room)}
+}
+
+// Room returns the live room called name, creating it when needed
+func (h *Hub) Room(name string) *Room {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	room, exists := h.rooms[name]
+	if !exists {
+		room = &Room{name: name, hub: h, clients: make(map[*Client]struct{})}
+		h.rooms[name] = room
+	}
+	return room
+}
+
+// Close disconnects every client of the live room called name and forgets
+// the room. Clients joining afterwards get a fresh room.
+func (h *Hub) Close(name string) {
+	h.mu.Lock()
+	room, exists := h.rooms[name]
+	delete(h.rooms, name)
+	h.mu.Unlock()
+
+	if exists {
+		room.closeAll()
+		Metrics.ChatConnections.DeleteLabelValues(name)
+	}
+}
+
+// Shutdown disconnects every client of every room and waits until their
+// read loops have returned, or until ctx is done. Call it once the HTTP
+// server no longer accepts connections.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	rooms := h.rooms
+	h.rooms = make(map[string]*Room)
+	h.mu.Unlock()
+
+	for _, room := range rooms {
+		room.closeAll()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.readers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Room is a live chatroom fanning messages out to its clients
+type Room struct {
+	name    string
+	hub     *Hub
+	mu      sync.RWMutex
+	clients map[*Client]struct{}
+}
+
+func (r *Room) Name() string {
+	return r.name
+}
+
+func (r *Room) ClientCount() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.clients)
+}
+
+// Join registers conn in the room and starts its write pump. history, loaded
+// by the caller beforehand so no database call holds up the room, is queued
+// before any later broadcast, so history and live messages never interleave.
+// The caller must run the client's ReadLoop.
+func (r *Room) Join(conn *websocket.Conn, history []interface{}) *Client {
+	client := newClient(r, conn)
+	r.hub.readers.Add(1)
+
+	encoded := make([][]byte, 0, len(history))
+	for _, message := range history {
+		if data, err := json.Marshal(message); err == nil {
+			encoded = append(encoded, data)
+		}
+	}
+
+	r.mu.Lock()
+	for _, data := range encoded {
+		client.enqueue(data)
+	}
+	r.clients[client] = struct{}{}
+	Metrics.ChatConnections.WithLabelValues(r.name).Inc()
+	r.mu.Unlock()
+
+	go client.writePump()
+	return client
+}
+
+// Broadcast sends message to every client without blocking. Clients whose
+// send queue is full are too slow to keep up and are evicted.
+func (r *Room) Broadcast(message interface{}) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		slog.Error("Could not encode chat message", "room", r.name, "error", err)
+		return
+	}
+
+	var slow []*Client
+	r.mu.RLock()
+	for client := range r.clients {
+		if !client.enqueue(data) {
+			slow = append(slow, client)
+		}
+	}
+	r.mu.RUnlock()
+
+	for _, client := range slow {
+		slog.Warn("Evicting slow chat client", "room", r.name)
+		r.leave(client)
+	}
+}
+
+// leave unregisters client and closes its send queue, which makes the write
+// pump close the connection. Safe to call more than once.
+func (r *Room) leave(client *Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.clients[client]; ok {
+		delete(r.clients, client)
+		close(client.send)
+		Metrics.ChatConnections.WithLabelValues(r.name).Dec()
+	}
+}
+
+// closeAll removes every client, their write pumps then close the connections
+func (r *Room) closeAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for client := range r.clients {
+		delete(r.clients, client)
+		close(client.send)
+		Metrics.ChatConnections.WithLabelValues(r.name).Dec()
+	}
+}
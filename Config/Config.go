@@ -0,0 +1,330 @@
+// Package Config holds the typed configuration of the backend. main loads it
+// once with Load and hands each subsystem its part; nothing else reads the
+// environment.
+package Config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is the whole configuration
+type Config struct {
+	HTTP      HTTP      `json:"http"`
+	Storage   Storage   `json:"storage"`
+	AI        AI        `json:"ai"`
+	Auth      Auth      `json:"auth"`
+	Mail      Mail      `json:"mail"`
+	RateLimit RateLimit `json:"rate_limit"`
+	Jobs      Jobs      `json:"jobs"`
+	Health    Health    `json:"health"`
+	Logging   Logging   `json:"logging"`
+}
+
+// HTTP configures the server and the links it mails out
+type HTTP struct {
+	Addr        string   `json:"addr"`
+	CORSOrigins []string `json:"cors_origins"`
+	// Proxies, as IPs or CIDRs, whose X-Forwarded-For header is believed.
+	// Empty trusts none, so the client IP is the connection's address and
+	// per IP limits cannot be dodged with a forged header.
+	TrustedProxies []string `json:"trusted_proxies"`
+	// Where /metrics is served, apart from the API so it is not public.
+	// Empty turns metrics off.
+	MetricsAddr string `json:"metrics_addr"`
+	// Where this API is reachable, for links to its endpoints
+	APIBaseURL string `json:"api_base_url"`
+	// Where the frontend is reachable, for links to its pages
+	AppBaseURL string `json:"app_base_url"`
+	// How long to keep serving after reporting not ready, so load balancers
+	// notice before connections are refused
+	DrainDelay Duration `json:"drain_delay"`
+	// How long shutdown waits for requests, chat clients and jobs to finish
+	ShutdownTimeout Duration `json:"shutdown_timeout"`
+}
+
+// Storage selects and locates the database. MongoDB has to be a replica set
+// or sharded cluster, likes are changed in transactions.
+type Storage struct {
+	Backend       string `json:"backend"` // mongo or memory
+	MongoURI      string `json:"mongo_uri"`
+	Database      string `json:"database"`
+	UsersDatabase string `json:"users_database"`
+}
+
+// AI configures the provider that moderates and answers posts
+type AI struct {
+	Provider         string   `json:"provider"` // openai, ollama or fake
+	BaseURL          string   `json:"base_url"` // Defaults to the provider's own
+	APIKey           string   `json:"api_key"`
+	Model            string   `json:"model"` // Defaults to the provider's own
+	FakeBlockedWords []string `json:"fake_blocked_words"`
+}
+
+// Auth configures tokens and account recovery
+type Auth struct {
+	// Empty means a random secret, so tokens die with the process
+	JWTSecret            string        `json:"jwt_secret"`
+	PasswordResetTTL     Duration      `json:"password_reset_ttl"`
+	EmailVerificationTTL Duration      `json:"email_verification_ttl"`
+	Login                LoginThrottle `json:"login"`
+}
+
+// LoginThrottle limits failed logins. From BackoffAfter failures on a key has
+// to wait BaseDelay, doubled for each further failure, and from LockoutAfter
+// failures on it has to wait Lockout. Failures older than Window are
+// forgotten. The IP counters key on the client IP, which behind a proxy is
+// only the real one with HTTP.TrustedProxies set, otherwise all clients
+// share the proxy's counter.
+type LoginThrottle struct {
+	BackoffAfter   int      `json:"backoff_after"`
+	LockoutAfter   int      `json:"lockout_after"`
+	IPBackoffAfter int      `json:"ip_backoff_after"`
+	IPLockoutAfter int      `json:"ip_lockout_after"`
+	BaseDelay      Duration `json:"base_delay"`
+	Lockout        Duration `json:"lockout"`
+	Window         Duration `json:"window"`
+}
+
+// Mail selects how emails are delivered
+type Mail struct {
+	Backend      string `json:"backend"` // smtp, file or log
+	Dir          string `json:"dir"`     // Where the file backend writes
+	From         string `json:"from"`
+	SMTPHost     string `json:"smtp_host"`
+	SMTPPort     string `json:"smtp_port"`
+	SMTPUsername string `json:"smtp_username"`
+	SMTPPassword string `json:"smtp_password"`
+}
+
+// RateLimit configures the token buckets of each route group
+type RateLimit struct {
+	// Empty keeps buckets in the storage backend, memory keeps them per instance
+	Backend string `json:"backend"`
+	// "<requests>/<duration>" or "off" by group name
+	Limits map[string]string `json:"limits"`
+}
+
+// Jobs configures the scheduled jobs
+type Jobs struct {
+	// Cron schedule or "off" by job name
+	Schedules    map[string]string `json:"schedules"`
+	LockOldPosts LockOldPosts      `json:"lock_old_posts"`
+}
+
+// LockOldPosts is the policy of the lock_old_posts job
+type LockOldPosts struct {
+	InactivityDays   int  `json:"inactivity_days"`
+	MinPostAgeDays   int  `json:"min_post_age_days"`
+	ExemptPinned     bool `json:"exempt_pinned"`
+	ExemptUnanswered bool `json:"exempt_unanswered"`
+	DryRun           bool `json:"dry_run"`
+}
+
+// Health configures the readiness checks
+type Health struct {
+	// Also ask the AI provider for a one token answer, which may cost money
+	CheckAI bool `json:"check_ai"`
+	// How long an AI check answers /readyz before it is asked again
+	AICheckInterval Duration `json:"ai_check_interval"`
+}
+
+// Logging configures the structured logger
+type Logging struct {
+	Level  string `json:"level"`  // debug, info, warn or error
+	Format string `json:"format"` // json or text
+}
+
+// Duration is a time.Duration written as "15m" in configuration files
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("duration must be a string like \"15m\": %v", err)
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Default returns the configuration used when nothing is set
+func Default() *Config {
+	return &Config{
+		HTTP: HTTP{
+			Addr:        "localhost:8080",
+			CORSOrigins: []string{"http://localhost:5173"},
+			APIBaseURL:  "http://localhost:8080",
+			AppBaseURL:  "http://localhost:5173",
+			// Zero, there is usually no load balancer in front of local runs
+			DrainDelay:      0,
+			ShutdownTimeout: Duration(15 * time.Second),
+			MetricsAddr:     "localhost:9090",
+		},
+		Storage: Storage{
+			Backend:       "mongo",
+			MongoURI:      "mongodb://localhost:27017",
+			Database:      "Pametni-Paketnik-baza",
+			UsersDatabase: "tezno_district",
+		},
+		AI: AI{
+			Provider: "openai",
+		},
+		Auth: Auth{
+			PasswordResetTTL:     Duration(time.Hour),
+			EmailVerificationTTL: Duration(48 * time.Hour),
+			Login: LoginThrottle{
+				BackoffAfter:   3,
+				LockoutAfter:   10,
+				IPBackoffAfter: 10,
+				IPLockoutAfter: 50,
+				BaseDelay:      Duration(time.Second),
+				Lockout:        Duration(15 * time.Minute),
+				Window:         Duration(time.Hour),
+			},
+		},
+		Mail: Mail{
+			Backend:  "log",
+			Dir:      "mail",
+			From:     "no-reply@localhost",
+			SMTPHost: "localhost",
+			SMTPPort: "587",
+		},
+		RateLimit: RateLimit{
+			Limits: map[string]string{
+				"auth":   "10/1m",
+				"writes": "60/1m",
+				"ai":     "10/1m",
+				"reads":  "300/1m",
+				"chat":   "20/1m",
+			},
+		},
+		Jobs: Jobs{
+			Schedules: map[string]string{
+				"lock_old_posts": "0 3 * * *",
+			},
+			LockOldPosts: LockOldPosts{
+				InactivityDays: 7,
+				MinPostAgeDays: 7,
+				ExemptPinned:   true,
+			},
+		},
+		Health: Health{
+			AICheckInterval: Duration(time.Minute),
+		},
+		Logging: Logging{
+			Level:  "info",
+			Format: "json",
+		},
+	}
+}
+
+// ParseRateLimit parses "<requests>/<duration>", e.g. "30/1m"
+func ParseRateLimit(spec string) (requests int, per time.Duration, err error) {
+	count, window, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, errRateLimitSpec
+	}
+
+	requests, err = strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || requests <= 0 {
+		return 0, 0, errRateLimitSpec
+	}
+	per, err = time.ParseDuration(strings.TrimSpace(window))
+	if err != nil || per <= 0 {
+		return 0, 0, errRateLimitSpec
+	}
+	return requests, per, nil
+}
+
+var errRateLimitSpec = errors.New("want <requests>/<duration>, e.g. 30/1m")
+
+// Validate reports every invalid setting at once. The AI credentials are left
+// to AI.Validate, only the server talks to the provider and tools sharing
+// the configuration must not need them.
+func (c *Config) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.HTTP.Addr != "", "http.addr is required")
+	check(c.HTTP.APIBaseURL != "", "http.api_base_url is required")
+	check(c.HTTP.AppBaseURL != "", "http.app_base_url is required")
+	check(c.HTTP.DrainDelay >= 0, "http.drain_delay cannot be negative")
+	check(c.HTTP.ShutdownTimeout > 0, "http.shutdown_timeout must be positive")
+
+	check(oneOf(c.Storage.Backend, "mongo", "memory"), "storage.backend %q is not mongo or memory", c.Storage.Backend)
+	if c.Storage.Backend == "mongo" {
+		check(c.Storage.MongoURI != "", "storage.mongo_uri is required with the mongo backend")
+		check(c.Storage.Database != "" && c.Storage.UsersDatabase != "", "storage.database and storage.users_database are required")
+	}
+
+	check(oneOf(c.AI.Provider, "openai", "ollama", "fake"), "ai.provider %q is not openai, ollama or fake", c.AI.Provider)
+
+	check(c.Auth.PasswordResetTTL > 0, "auth.password_reset_ttl must be positive")
+	check(c.Auth.EmailVerificationTTL > 0, "auth.email_verification_ttl must be positive")
+	login := c.Auth.Login
+	check(login.BackoffAfter > 0 && login.LockoutAfter > 0 && login.IPBackoffAfter > 0 && login.IPLockoutAfter > 0,
+		"auth.login failure counts must be positive")
+	check(login.BaseDelay > 0 && login.Lockout > 0 && login.Window > 0, "auth.login durations must be positive")
+
+	check(oneOf(c.Mail.Backend, "smtp", "file", "log"), "mail.backend %q is not smtp, file or log", c.Mail.Backend)
+	check(c.Mail.Backend != "smtp" || c.Mail.SMTPHost != "", "mail.smtp_host is required with the smtp backend")
+	check(c.Mail.Backend != "file" || c.Mail.Dir != "", "mail.dir is required with the file backend")
+
+	check(oneOf(c.RateLimit.Backend, "", "memory"), "rate_limit.backend %q is not empty or memory", c.RateLimit.Backend)
+	for group, spec := range c.RateLimit.Limits {
+		if spec != "off" {
+			_, _, err := ParseRateLimit(spec)
+			check(err == nil, "rate_limit.limits.%s %q: %v", group, spec, err)
+		}
+	}
+
+	check(!c.Health.CheckAI || c.Health.AICheckInterval > 0, "health.ai_check_interval must be positive")
+
+	check(oneOf(strings.ToLower(c.Logging.Level), "debug", "info", "warn", "error"), "logging.level %q is not debug, info, warn or error", c.Logging.Level)
+	check(oneOf(c.Logging.Format, "json", "text"), "logging.format %q is not json or text", c.Logging.Format)
+
+	policy := c.Jobs.LockOldPosts
+	check(policy.InactivityDays >= 0 && policy.MinPostAgeDays >= 0, "jobs.lock_old_posts days cannot be negative")
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Validate checks what calling the provider needs
+func (a AI) Validate() error {
+	if a.Provider == "openai" && a.APIKey == "" {
+		return errors.New("invalid configuration: ai.api_key is required with the openai provider")
+	}
+	return nil
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
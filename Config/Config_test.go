@@ -0,0 +1,158 @@
+package Config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		change       func(c *Config)
+		wantProblems []string
+	}{
+		{name: "defaults", change: func(c *Config) {}},
+		{name: "memory storage needs no URI", change: func(c *Config) { c.Storage.Backend = "memory"; c.Storage.MongoURI = "" }},
+		{name: "rate limit off", change: func(c *Config) { c.RateLimit.Limits["reads"] = "off" }},
+		{
+			name:         "missing base URLs",
+			change:       func(c *Config) { c.HTTP.APIBaseURL = ""; c.HTTP.AppBaseURL = "" },
+			wantProblems: []string{"http.api_base_url is required", "http.app_base_url is required"},
+		},
+		{
+			name:         "negative and zero durations",
+			change:       func(c *Config) { c.HTTP.DrainDelay = Duration(-time.Second); c.HTTP.ShutdownTimeout = 0 },
+			wantProblems: []string{"http.drain_delay cannot be negative", "http.shutdown_timeout must be positive"},
+		},
+		{
+			name:         "missing databases",
+			change:       func(c *Config) { c.Storage.UsersDatabase = "" },
+			wantProblems: []string{"storage.database and storage.users_database are required"},
+		},
+		{
+			name:         "unknown provider",
+			change:       func(c *Config) { c.AI.Provider = "llama" },
+			wantProblems: []string{`ai.provider "llama"`},
+		},
+		{
+			name:         "file mail without a directory",
+			change:       func(c *Config) { c.Mail.Backend = "file"; c.Mail.Dir = "" },
+			wantProblems: []string{"mail.dir is required"},
+		},
+		{
+			name:         "unknown rate limit backend",
+			change:       func(c *Config) { c.RateLimit.Backend = "redis" },
+			wantProblems: []string{`rate_limit.backend "redis"`},
+		},
+		{
+			name:         "AI check without an interval",
+			change:       func(c *Config) { c.Health.CheckAI = true; c.Health.AICheckInterval = 0 },
+			wantProblems: []string{"health.ai_check_interval must be positive"},
+		},
+		{
+			name:         "logging",
+			change:       func(c *Config) { c.Logging.Level = "loud"; c.Logging.Format = "xml" },
+			wantProblems: []string{`logging.level "loud"`, `logging.format "xml"`},
+		},
+		{
+			name:         "negative lock policy",
+			change:       func(c *Config) { c.Jobs.LockOldPosts.InactivityDays = -1 },
+			wantProblems: []string{"jobs.lock_old_posts days cannot be negative"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Default()
+			tt.change(config)
+
+			err := config.Validate()
+			if len(tt.wantProblems) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() = nil, want %q", tt.wantProblems)
+			}
+			// Every problem is reported at once
+			for _, problem := range tt.wantProblems {
+				if !strings.Contains(err.Error(), problem) {
+					t.Errorf("Validate() = %q, want it to contain %q", err, problem)
+				}
+			}
+		})
+	}
+}
+
+func TestAIValidate(t *testing.T) {
+	tests := []struct {
+		ai      AI
+		wantErr bool
+	}{
+		{AI{Provider: "openai"}, true},
+		{AI{Provider: "openai", APIKey: "key"}, false},
+		{AI{Provider: "ollama"}, false},
+		{AI{Provider: "fake"}, false},
+	}
+
+	for _, tt := range tests {
+		if err := tt.ai.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%+v: Validate() = %v, want error %v", tt.ai, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		spec         string
+		wantRequests int
+		wantPer      time.Duration
+		wantErr      bool
+	}{
+		{spec: "30/1m", wantRequests: 30, wantPer: time.Minute},
+		{spec: " 5 / 10s ", wantRequests: 5, wantPer: 10 * time.Second},
+		{spec: "30", wantErr: true},
+		{spec: "x/1m", wantErr: true},
+		{spec: "0/1m", wantErr: true},
+		{spec: "10/soon", wantErr: true},
+		{spec: "10/-1m", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		requests, per, err := ParseRateLimit(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRateLimit(%q) error = %v, want error %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if requests != tt.wantRequests || per != tt.wantPer {
+			t.Errorf("ParseRateLimit(%q) = %d, %v, want %d, %v", tt.spec, requests, per, tt.wantRequests, tt.wantPer)
+		}
+	}
+}
+
+func TestDurationJSON(t *testing.T) {
+	var d Duration
+	if err := d.UnmarshalJSON([]byte(`"1h30m"`)); err != nil {
+		t.Fatal(err)
+	}
+	if time.Duration(d) != 90*time.Minute {
+		t.Errorf("parsed %v, want 1h30m", d)
+	}
+
+	data, err := d.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `"1h30m0s"` {
+		t.Errorf("marshalled %s, want \"1h30m0s\"", data)
+	}
+
+	for _, bad := range []string{`90`, `"90"`, `null`} {
+		if err := d.UnmarshalJSON([]byte(bad)); err == nil {
+			t.Errorf("UnmarshalJSON(%s) succeeded", bad)
+		}
+	}
+}
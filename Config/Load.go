@@ -0,0 +1,225 @@
+package Config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Load builds the configuration from, in increasing order of precedence, the
+// defaults, the JSON file named by -config or CONFIG_FILE, the environment
+// (including a .env file when there is one) and the flags in args. The
+// result is validated.
+func Load(args []string) (*Config, error) {
+	// Variables already set win over .env, and the file is optional
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("could not read .env: %v", err)
+	}
+
+	flags := flag.NewFlagSet("backend", flag.ContinueOnError)
+	file := flags.String("config", os.Getenv("CONFIG_FILE"), "JSON configuration file")
+	addr := flags.String("addr", "", "address to listen on, e.g. :8080")
+	storage := flags.String("storage", "", "storage backend, mongo or memory")
+	aiProvider := flags.String("ai-provider", "", "AI provider, openai, ollama or fake")
+	mailer := flags.String("mailer", "", "mail backend, smtp, file or log")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	config := Default()
+	if *file != "" {
+		if err := config.loadFile(*file); err != nil {
+			return nil, err
+		}
+	}
+	if err := config.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	for target, value := range map[*string]string{
+		&config.HTTP.Addr:       *addr,
+		&config.Storage.Backend: *storage,
+		&config.AI.Provider:     *aiProvider,
+		&config.Mail.Backend:    *mailer,
+	} {
+		if value != "" {
+			*target = value
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read config file: %v", err)
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("could not parse config file %s: %v", path, err)
+	}
+	return nil
+}
+
+// envBinding sets one setting from the environment variable Key
+type envBinding struct {
+	Key   string
+	Apply func(value string) error
+}
+
+// loadEnv applies every environment variable that is set, .env.example
+// lists them.
+func (c *Config) loadEnv() error {
+	bindings := []envBinding{
+		{"LISTEN_ADDR", setString(&c.HTTP.Addr)},
+		{"CORS_ORIGINS", setList(&c.HTTP.CORSOrigins)},
+		{"TRUSTED_PROXIES", setList(&c.HTTP.TrustedProxies)},
+		{"METRICS_ADDR", setString(&c.HTTP.MetricsAddr)},
+		{"API_BASE_URL", setString(&c.HTTP.APIBaseURL)},
+		{"APP_BASE_URL", setString(&c.HTTP.AppBaseURL)},
+		{"SHUTDOWN_DRAIN_DELAY_SECONDS", setUnits(&c.HTTP.DrainDelay, time.Second)},
+		{"SHUTDOWN_TIMEOUT_SECONDS", setUnits(&c.HTTP.ShutdownTimeout, time.Second)},
+
+		{"STORAGE_BACKEND", setLower(&c.Storage.Backend)},
+		{"MONGO_URI", setString(&c.Storage.MongoURI)},
+		{"MONGO_DATABASE", setString(&c.Storage.Database)},
+		{"MONGO_USERS_DATABASE", setString(&c.Storage.UsersDatabase)},
+
+		{"AI_PROVIDER", setLower(&c.AI.Provider)},
+		{"AI_BASE_URL", setString(&c.AI.BaseURL)},
+		{"AI_API_KEY", setString(&c.AI.APIKey)},
+		{"AI_MODEL", setString(&c.AI.Model)},
+		{"AI_FAKE_BLOCKED_WORDS", setList(&c.AI.FakeBlockedWords)},
+
+		{"JWT_SECRET", setString(&c.Auth.JWTSecret)},
+		{"PASSWORD_RESET_TTL_MINUTES", setUnits(&c.Auth.PasswordResetTTL, time.Minute)},
+		{"EMAIL_VERIFICATION_TTL_HOURS", setUnits(&c.Auth.EmailVerificationTTL, time.Hour)},
+		{"LOGIN_BACKOFF_AFTER", setInt(&c.Auth.Login.BackoffAfter)},
+		{"LOGIN_LOCKOUT_AFTER", setInt(&c.Auth.Login.LockoutAfter)},
+		{"LOGIN_IP_BACKOFF_AFTER", setInt(&c.Auth.Login.IPBackoffAfter)},
+		{"LOGIN_IP_LOCKOUT_AFTER", setInt(&c.Auth.Login.IPLockoutAfter)},
+		{"LOGIN_BACKOFF_SECONDS", setUnits(&c.Auth.Login.BaseDelay, time.Second)},
+		{"LOGIN_LOCKOUT_MINUTES", setUnits(&c.Auth.Login.Lockout, time.Minute)},
+		{"LOGIN_FAILURE_WINDOW_MINUTES", setUnits(&c.Auth.Login.Window, time.Minute)},
+
+		{"MAILER", setLower(&c.Mail.Backend)},
+		{"MAILER_DIR", setString(&c.Mail.Dir)},
+		{"MAIL_FROM", setString(&c.Mail.From)},
+		{"SMTP_HOST", setString(&c.Mail.SMTPHost)},
+		{"SMTP_PORT", setString(&c.Mail.SMTPPort)},
+		{"SMTP_USERNAME", setString(&c.Mail.SMTPUsername)},
+		{"SMTP_PASSWORD", setString(&c.Mail.SMTPPassword)},
+
+		{"RATE_LIMIT_BACKEND", setLower(&c.RateLimit.Backend)},
+
+		{"LOCK_INACTIVITY_DAYS", setInt(&c.Jobs.LockOldPosts.InactivityDays)},
+		{"LOCK_MIN_POST_AGE_DAYS", setInt(&c.Jobs.LockOldPosts.MinPostAgeDays)},
+		{"LOCK_EXEMPT_PINNED", setBool(&c.Jobs.LockOldPosts.ExemptPinned)},
+		{"LOCK_EXEMPT_UNANSWERED", setBool(&c.Jobs.LockOldPosts.ExemptUnanswered)},
+		{"LOCK_DRY_RUN", setBool(&c.Jobs.LockOldPosts.DryRun)},
+
+		{"READYZ_CHECK_AI", setBool(&c.Health.CheckAI)},
+		{"READYZ_AI_CHECK_INTERVAL_SECONDS", setUnits(&c.Health.AICheckInterval, time.Second)},
+
+		{"LOG_LEVEL", setLower(&c.Logging.Level)},
+		{"LOG_FORMAT", setLower(&c.Logging.Format)},
+	}
+
+	// RATE_LIMIT_<GROUP> and JOB_<NAME>_SCHEDULE for every known group and job
+	for group := range c.RateLimit.Limits {
+		bindings = append(bindings, envBinding{"RATE_LIMIT_" + strings.ToUpper(group), setEntry(c.RateLimit.Limits, group)})
+	}
+	for job := range c.Jobs.Schedules {
+		bindings = append(bindings, envBinding{"JOB_" + strings.ToUpper(job) + "_SCHEDULE", setEntry(c.Jobs.Schedules, job)})
+	}
+
+	for _, binding := range bindings {
+		value, ok := os.LookupEnv(binding.Key)
+		if !ok || value == "" {
+			continue
+		}
+		if err := binding.Apply(value); err != nil {
+			return fmt.Errorf("invalid %s %q: %v", binding.Key, value, err)
+		}
+	}
+	return nil
+}
+
+func setString(target *string) func(string) error {
+	return func(value string) error {
+		*target = value
+		return nil
+	}
+}
+
+func setLower(target *string) func(string) error {
+	return func(value string) error {
+		*target = strings.ToLower(value)
+		return nil
+	}
+}
+
+// setList splits a comma separated list
+func setList(target *[]string) func(string) error {
+	return func(value string) error {
+		*target = nil
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*target = append(*target, item)
+			}
+		}
+		return nil
+	}
+}
+
+func setInt(target *int) func(string) error {
+	return func(value string) error {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*target = parsed
+		return nil
+	}
+}
+
+func setBool(target *bool) func(string) error {
+	return func(value string) error {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*target = parsed
+		return nil
+	}
+}
+
+// setUnits reads a whole number of unit, e.g. minutes
+func setUnits(target *Duration, unit time.Duration) func(string) error {
+	return func(value string) error {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*target = Duration(time.Duration(parsed) * unit)
+		return nil
+	}
+}
+
+func setEntry(target map[string]string, key string) func(string) error {
+	return func(value string) error {
+		target[key] = value
+		return nil
+	}
+}
@@ -0,0 +1,162 @@
+package Config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigFile writes a JSON configuration file and returns its path
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := writeConfigFile(t, `{
+		"http": {"addr": ":7000"},
+		"logging": {"level": "debug"},
+		"auth": {"login": {"base_delay": "2s"}},
+		"rate_limit": {"limits": {"auth": "5/1m"}}
+	}`)
+
+	tests := []struct {
+		name          string
+		env           map[string]string
+		args          []string
+		wantAddr      string
+		wantLevel     string
+		wantBaseDelay time.Duration
+		wantAuthLimit string
+	}{
+		{
+			name:          "file over defaults",
+			wantAddr:      ":7000",
+			wantLevel:     "debug",
+			wantBaseDelay: 2 * time.Second,
+			wantAuthLimit: "5/1m",
+		},
+		{
+			name:          "environment over file",
+			env:           map[string]string{"LISTEN_ADDR": ":7001", "LOG_LEVEL": "WARN", "LOGIN_BACKOFF_SECONDS": "3", "RATE_LIMIT_AUTH": "off"},
+			wantAddr:      ":7001",
+			wantLevel:     "warn",
+			wantBaseDelay: 3 * time.Second,
+			wantAuthLimit: "off",
+		},
+		{
+			name:          "flags over environment",
+			env:           map[string]string{"LISTEN_ADDR": ":7001"},
+			args:          []string{"-addr", ":7002"},
+			wantAddr:      ":7002",
+			wantLevel:     "debug",
+			wantBaseDelay: 2 * time.Second,
+			wantAuthLimit: "5/1m",
+		},
+		{
+			name:          "empty variables are ignored",
+			env:           map[string]string{"LISTEN_ADDR": "", "LOG_LEVEL": ""},
+			wantAddr:      ":7000",
+			wantLevel:     "debug",
+			wantBaseDelay: 2 * time.Second,
+			wantAuthLimit: "5/1m",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			config, err := Load(append([]string{"-config", file}, tt.args...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.HTTP.Addr != tt.wantAddr {
+				t.Errorf("http.addr = %q, want %q", config.HTTP.Addr, tt.wantAddr)
+			}
+			if config.Logging.Level != tt.wantLevel {
+				t.Errorf("logging.level = %q, want %q", config.Logging.Level, tt.wantLevel)
+			}
+			if got := time.Duration(config.Auth.Login.BaseDelay); got != tt.wantBaseDelay {
+				t.Errorf("auth.login.base_delay = %v, want %v", got, tt.wantBaseDelay)
+			}
+			if got := config.RateLimit.Limits["auth"]; got != tt.wantAuthLimit {
+				t.Errorf("rate_limit.limits.auth = %q, want %q", got, tt.wantAuthLimit)
+			}
+			// Settings nobody set keep their defaults
+			if got := config.RateLimit.Limits["reads"]; got != "300/1m" {
+				t.Errorf("rate_limit.limits.reads = %q, want the default 300/1m", got)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFileFromEnvironment(t *testing.T) {
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, `{"http": {"addr": ":7100"}}`))
+
+	config, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.HTTP.Addr != ":7100" {
+		t.Errorf("http.addr = %q, want :7100", config.HTTP.Addr)
+	}
+}
+
+func TestLoadRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		args    []string
+		wantErr string
+	}{
+		{name: "not a number", env: map[string]string{"LOGIN_BACKOFF_AFTER": "three"}, wantErr: `invalid LOGIN_BACKOFF_AFTER "three"`},
+		{name: "not a boolean", env: map[string]string{"LOCK_DRY_RUN": "maybe"}, wantErr: `invalid LOCK_DRY_RUN "maybe"`},
+		{name: "not whole units", env: map[string]string{"SHUTDOWN_TIMEOUT_SECONDS": "1.5"}, wantErr: "invalid SHUTDOWN_TIMEOUT_SECONDS"},
+		{name: "unknown backend", env: map[string]string{"STORAGE_BACKEND": "sqlite"}, wantErr: `storage.backend "sqlite"`},
+		{name: "bad rate limit", env: map[string]string{"RATE_LIMIT_AUTH": "lots"}, wantErr: `rate_limit.limits.auth "lots"`},
+		{name: "non positive count", env: map[string]string{"LOGIN_LOCKOUT_AFTER": "0"}, wantErr: "auth.login failure counts must be positive"},
+		{name: "unknown flag backend", args: []string{"-storage", "files"}, wantErr: `storage.backend "files"`},
+		{name: "unknown flag", args: []string{"-port", "80"}, wantErr: "flag provided but not defined"},
+		{name: "missing file", file: "missing", wantErr: "could not read config file"},
+		{name: "invalid JSON", file: `{"http":`, wantErr: "could not parse config file"},
+		{name: "duration without unit", file: `{"auth": {"login": {"lockout": 900}}}`, wantErr: "duration must be a string"},
+		{name: "missing address", file: `{"http": {"addr": ""}}`, wantErr: "http.addr is required"},
+		{name: "missing mongo URI", file: `{"storage": {"mongo_uri": ""}}`, wantErr: "storage.mongo_uri is required"},
+		{name: "missing SMTP host", file: `{"mail": {"backend": "smtp", "smtp_host": ""}}`, wantErr: "mail.smtp_host is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			args := tt.args
+			switch tt.file {
+			case "":
+			case "missing":
+				args = append(args, "-config", filepath.Join(t.TempDir(), "missing.json"))
+			default:
+				args = append(args, "-config", writeConfigFile(t, tt.file))
+			}
+
+			_, err := Load(args)
+			if err == nil {
+				t.Fatalf("Load succeeded, want an error containing %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+// Package Errors holds the typed errors handlers fail with and renders them
+// as one JSON envelope on every route:
+//
+//	{"error": {"code": "validation", "message": "...", "fields": {...}, "details": {...}, "request_id": "..."}}
+//
+// Handlers call Abort with an *Error and return; Middleware writes the
+// response. Any other error is answered as internal without its text.
+package Errors
+
+import (
+	"backend/Logging"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Code tells clients what went wrong without parsing the message
+type Code string
+
+const (
+	CodeValidation    Code = "validation"     // The request is malformed or a field is invalid
+	CodeUnauthorized  Code = "unauthorized"   // No valid credentials
+	CodeForbidden     Code = "forbidden"      // Authenticated but not allowed
+	CodeNotFound      Code = "not_found"      // The route or resource does not exist
+	CodeConflict      Code = "conflict"       // Clashes with the current state, e.g. a taken username
+	CodeLocked        Code = "locked"         // The post is locked
+	CodeRateLimited   Code = "rate_limited"   // Too many requests, see details.retry_after
+	CodeAIUnavailable Code = "ai_unavailable" // The AI provider failed or did not answer
+	CodeInternal      Code = "internal"       // Anything else, details are only logged
+)
+
+// statuses is the HTTP status of each code
+var statuses = map[Code]int{
+	CodeValidation:    http.StatusBadRequest,
+	CodeUnauthorized:  http.StatusUnauthorized,
+	CodeForbidden:     http.StatusForbidden,
+	CodeNotFound:      http.StatusNotFound,
+	CodeConflict:      http.StatusConflict,
+	CodeLocked:        http.StatusLocked,
+	CodeRateLimited:   http.StatusTooManyRequests,
+	CodeAIUnavailable: http.StatusServiceUnavailable,
+	CodeInternal:      http.StatusInternalServerError,
+}
+
+// Error is an error meant for the client. Message is shown as is, Err is the
+// cause and only ever logged.
+type Error struct {
+	Code    Code
+	Message string
+	Fields  map[string]string // Problems with single request fields, by JSON name
+	Details map[string]any    // Extra information, e.g. retry_after
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Status is the HTTP status the error is answered with
+func (e *Error) Status() int {
+	if status, ok := statuses[e.Code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// WithField adds a problem with the request field name
+func (e *Error) WithField(name string, problem string) *Error {
+	if e.Fields == nil {
+		e.Fields = map[string]string{}
+	}
+	e.Fields[name] = problem
+	return e
+}
+
+// WithDetail adds extra information for the client
+func (e *Error) WithDetail(key string, value any) *Error {
+	if e.Details == nil {
+		e.Details = map[string]any{}
+	}
+	e.Details[key] = value
+	return e
+}
+
+// New returns an error with code and message
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func Validation(message string) *Error {
+	return New(CodeValidation, message)
+}
+
+// Field is a validation error of a single request field, the problem is
+// also the message
+func Field(name string, problem string) *Error {
+	return Validation(problem).WithField(name, problem)
+}
+
+func Unauthorized(message string) *Error {
+	return New(CodeUnauthorized, message)
+}
+
+func Forbidden(message string) *Error {
+	return New(CodeForbidden, message)
+}
+
+func NotFound(message string) *Error {
+	return New(CodeNotFound, message)
+}
+
+func Conflict(message string) *Error {
+	return New(CodeConflict, message)
+}
+
+func Locked(message string) *Error {
+	return New(CodeLocked, message)
+}
+
+// RateLimited tells the client to wait retryAfter seconds. Callers also set
+// the Retry-After header.
+func RateLimited(message string, retryAfter int) *Error {
+	return New(CodeRateLimited, message).WithDetail("retry_after", retryAfter)
+}
+
+// AIUnavailable reports a failed call to the AI provider
+func AIUnavailable(err error) *Error {
+	return &Error{Code: CodeAIUnavailable, Message: "AI service is unavailable, try again later", Err: err}
+}
+
+// Internal reports a failure that is not the client's fault. message says
+// what failed without details, err is logged.
+func Internal(message string, err error) *Error {
+	return &Error{Code: CodeInternal, Message: message, Err: err}
+}
+
+// InvalidBody describes why a JSON request body could not be bound, naming
+// the field when it has the wrong type
+func InvalidBody(err error) *Error {
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.Is(err, io.EOF):
+		return &Error{Code: CodeValidation, Message: "Request body is required", Err: err}
+	case errors.As(err, &syntaxErr):
+		return &Error{Code: CodeValidation, Message: "Request body is not valid JSON", Err: err}
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		e := Field(typeErr.Field, typeErr.Field+" must be "+kindName(typeErr.Type))
+		e.Err = err
+		return e
+	}
+	return &Error{Code: CodeValidation, Message: "Invalid request body", Err: err}
+}
+
+// kindName describes a Go type the way a JSON client would
+func kindName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+// As returns err as an *Error, wrapping anything else as internal
+func As(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return Internal("Internal server error", err)
+}
+
+// Envelope is the body of every error response
+type Envelope struct {
+	Error Body `json:"error"`
+}
+
+type Body struct {
+	Code      Code              `json:"code"`
+	Message   string            `json:"message"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	Details   map[string]any    `json:"details,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+}
+
+// Envelope returns the body sent for e in answer to c, also for messages
+// over a WebSocket
+func (e *Error) Envelope(c *gin.Context) Envelope {
+	return Envelope{Error: Body{
+		Code:      e.Code,
+		Message:   e.Message,
+		Fields:    e.Fields,
+		Details:   e.Details,
+		RequestID: c.Writer.Header().Get(Logging.RequestIDHeader),
+	}}
+}
+
+// Abort stops the handler chain with err, which Middleware answers
+func Abort(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// Middleware answers the last error of the chain, unless a response was
+// already written. Internal errors are logged with their cause.
+func Middleware(c *gin.Context) {
+	c.Next()
+
+	if len(c.Errors) == 0 || c.Writer.Written() {
+		return
+	}
+
+	e := As(c.Errors.Last().Err)
+	if e.Status() >= http.StatusInternalServerError {
+		Logging.FromContext(c.Request.Context()).Error(e.Message, "code", e.Code, "error", e.Err)
+	}
+	c.JSON(e.Status(), e.Envelope(c))
+}
+
+// Recovery logs a panic with the request's logger and answers it as
+// internal, for gin.CustomRecovery
+func Recovery(c *gin.Context, recovered any) {
+	Logging.FromContext(c.Request.Context()).Error("panic", "error", recovered, "path", c.Request.URL.Path)
+	if c.Writer.Written() {
+		c.Abort()
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusInternalServerError, Internal("Internal server error", nil).Envelope(c))
+}
+
+// NoRoute answers requests no route matches
+func NoRoute(c *gin.Context) {
+	Abort(c, NotFound("Route not found"))
+}
@@ -0,0 +1,232 @@
+package Errors
+
+import (
+	"backend/Logging"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		err        *Error
+		wantCode   Code
+		wantStatus int
+	}{
+		{Validation("bad"), CodeValidation, http.StatusBadRequest},
+		{Field("name", "name is required"), CodeValidation, http.StatusBadRequest},
+		{Unauthorized("who"), CodeUnauthorized, http.StatusUnauthorized},
+		{Forbidden("no"), CodeForbidden, http.StatusForbidden},
+		{NotFound("gone"), CodeNotFound, http.StatusNotFound},
+		{Conflict("taken"), CodeConflict, http.StatusConflict},
+		{Locked("locked"), CodeLocked, http.StatusLocked},
+		{RateLimited("slow down", 5), CodeRateLimited, http.StatusTooManyRequests},
+		{AIUnavailable(errors.New("timeout")), CodeAIUnavailable, http.StatusServiceUnavailable},
+		{Internal("failed", errors.New("boom")), CodeInternal, http.StatusInternalServerError},
+		{New("made_up", "unknown"), "made_up", http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		if tt.err.Code != tt.wantCode {
+			t.Errorf("%q: code = %q, want %q", tt.err.Message, tt.err.Code, tt.wantCode)
+		}
+		if got := tt.err.Status(); got != tt.wantStatus {
+			t.Errorf("%q: status = %d, want %d", tt.err.Message, got, tt.wantStatus)
+		}
+	}
+}
+
+func TestInvalidBody(t *testing.T) {
+	type body struct {
+		Name  string   `json:"name"`
+		Count int      `json:"count"`
+		Tags  []string `json:"tags"`
+	}
+
+	tests := []struct {
+		name        string
+		body        string
+		wantMessage string
+		wantFields  map[string]string
+	}{
+		{"empty", "", "Request body is required", nil},
+		{"truncated", "{", "Invalid request body", nil},
+		{"not JSON", "{name}", "Request body is not valid JSON", nil},
+		{"string for number", `{"count":"3"}`, "count must be a number", map[string]string{"count": "count must be a number"}},
+		{"number for string", `{"name":3}`, "name must be a string", map[string]string{"name": "name must be a string"}},
+		{"object for array", `{"tags":{}}`, "tags must be an array", map[string]string{"tags": "tags must be an array"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b body
+			bindErr := json.NewDecoder(strings.NewReader(tt.body)).Decode(&b)
+			if bindErr == nil {
+				t.Fatal("decoding succeeded")
+			}
+
+			e := InvalidBody(bindErr)
+			if e.Code != CodeValidation {
+				t.Errorf("code = %q, want validation", e.Code)
+			}
+			if e.Message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", e.Message, tt.wantMessage)
+			}
+			if len(e.Fields) != len(tt.wantFields) {
+				t.Errorf("fields = %v, want %v", e.Fields, tt.wantFields)
+			}
+			for field, problem := range tt.wantFields {
+				if e.Fields[field] != problem {
+					t.Errorf("fields[%s] = %q, want %q", field, e.Fields[field], problem)
+				}
+			}
+			if !errors.Is(e, bindErr) {
+				t.Error("the decoding error is not the cause")
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    gin.HandlerFunc
+		wantStatus int
+		wantBody   Body
+	}{
+		{
+			name:       "field error",
+			handler:    func(c *gin.Context) { Abort(c, Field("title", "title is required")) },
+			wantStatus: http.StatusBadRequest,
+			wantBody: Body{
+				Code:    CodeValidation,
+				Message: "title is required",
+				Fields:  map[string]string{"title": "title is required"},
+			},
+		},
+		{
+			name:       "details",
+			handler:    func(c *gin.Context) { Abort(c, RateLimited("Too many requests", 7)) },
+			wantStatus: http.StatusTooManyRequests,
+			wantBody: Body{
+				Code:    CodeRateLimited,
+				Message: "Too many requests",
+				Details: map[string]any{"retry_after": float64(7)},
+			},
+		},
+		{
+			name:       "internal error hides its cause",
+			handler:    func(c *gin.Context) { Abort(c, Internal("Error loading posts", errors.New("connection refused"))) },
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   Body{Code: CodeInternal, Message: "Error loading posts"},
+		},
+		{
+			name:       "plain errors are internal",
+			handler:    func(c *gin.Context) { Abort(c, errors.New("secret detail")) },
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   Body{Code: CodeInternal, Message: "Internal server error"},
+		},
+		{
+			name:       "last error wins",
+			handler:    func(c *gin.Context) { _ = c.Error(Conflict("first")); Abort(c, NotFound("Post not found")) },
+			wantStatus: http.StatusNotFound,
+			wantBody:   Body{Code: CodeNotFound, Message: "Post not found"},
+		},
+		{
+			name:       "panics are internal",
+			handler:    func(c *gin.Context) { panic("boom") },
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   Body{Code: CodeInternal, Message: "Internal server error"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newTestRouter()
+			router.GET("/", tt.handler)
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.Header.Set(Logging.RequestIDHeader, "req-1")
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+			tt.wantBody.RequestID = "req-1"
+			checkEnvelope(t, recorder, tt.wantBody)
+			if strings.Contains(recorder.Body.String(), "connection refused") || strings.Contains(recorder.Body.String(), "secret detail") {
+				t.Errorf("body leaks the cause: %s", recorder.Body)
+			}
+		})
+	}
+}
+
+func TestMiddlewareKeepsWrittenResponses(t *testing.T) {
+	router := newTestRouter()
+	router.GET("/", func(c *gin.Context) {
+		c.String(http.StatusAccepted, "done")
+		_ = c.Error(errors.New("logged only"))
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	if recorder.Code != http.StatusAccepted || recorder.Body.String() != "done" {
+		t.Errorf("response = %d %q, want 202 done", recorder.Code, recorder.Body)
+	}
+}
+
+func TestNoRoute(t *testing.T) {
+	router := newTestRouter()
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", recorder.Code)
+	}
+	checkEnvelope(t, recorder, Body{Code: CodeNotFound, Message: "Route not found", RequestID: recorder.Header().Get(Logging.RequestIDHeader)})
+}
+
+// newTestRouter returns a router set up like the server's
+func newTestRouter() *gin.Engine {
+	router := gin.New()
+	router.Use(Logging.Middleware, gin.CustomRecovery(Recovery), Middleware)
+	router.NoRoute(NoRoute)
+	return router
+}
+
+// checkEnvelope compares the error envelope in recorder with want
+func checkEnvelope(t *testing.T, recorder *httptest.ResponseRecorder, want Body) {
+	t.Helper()
+
+	var envelope Envelope
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("decoding %q: %v", recorder.Body, err)
+	}
+	got := envelope.Error
+	if got.Code != want.Code || got.Message != want.Message || got.RequestID != want.RequestID {
+		t.Errorf("body = %+v, want %+v", got, want)
+	}
+	if len(got.Fields) != len(want.Fields) || len(got.Details) != len(want.Details) {
+		t.Errorf("fields, details = %v, %v, want %v, %v", got.Fields, got.Details, want.Fields, want.Details)
+	}
+	for field, problem := range want.Fields {
+		if got.Fields[field] != problem {
+			t.Errorf("fields[%s] = %q, want %q", field, got.Fields[field], problem)
+		}
+	}
+	for key, value := range want.Details {
+		if got.Details[key] != value {
+			t.Errorf("details[%s] = %v, want %v", key, got.Details[key], value)
+		}
+	}
+}
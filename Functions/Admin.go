@@ -0,0 +1,99 @@
+package Functions
+
+import (
+	"backend/Errors"
+	"backend/Logging"
+	"backend/Repository"
+	"backend/Schemas"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// findTargetUser loads the user named by the :username path parameter
+func findTargetUser(c *gin.Context) (Schemas.User, error) {
+	target, err := Repository.Users().FindByUsername(c, c.Param("username"))
+	if errors.Is(err, Repository.ErrNotFound) {
+		return Schemas.User{}, Errors.NotFound("User not found")
+	}
+	if err != nil {
+		return Schemas.User{}, Errors.Internal("Error loading user", err)
+	}
+	return target, nil
+}
+
+// BanUser stops a user from logging in or using their tokens
+func BanUser(c *gin.Context) {
+	setBanned(c, true)
+}
+
+// UnbanUser lifts a ban
+func UnbanUser(c *gin.Context) {
+	setBanned(c, false)
+}
+
+func setBanned(c *gin.Context, banned bool) {
+	target, err := findTargetUser(c)
+	if err != nil {
+		Errors.Abort(c, err)
+		return
+	}
+
+	// Nobody bans themselves or someone of equal or higher rank
+	actor, _ := CurrentUser(c)
+	if target.ID == actor.ID || roleRank(roleOf(target)) >= roleRank(roleOf(actor)) {
+		Errors.Abort(c, Errors.Forbidden("You are not allowed to ban this user"))
+		return
+	}
+
+	if err := Repository.Users().SetBanned(c, target.ID, banned); err != nil {
+		Errors.Abort(c, Errors.Internal("Error updating user", err))
+		return
+	}
+
+	if banned {
+		Logging.FromContext(c).Info("User banned", "target_user", target.Name)
+		c.JSON(http.StatusOK, gin.H{"message": "User banned successfully"})
+		return
+	}
+	Logging.FromContext(c).Info("User unbanned", "target_user", target.Name)
+	c.JSON(http.StatusOK, gin.H{"message": "User unbanned successfully"})
+}
+
+// SetUserRole changes a user's role
+func SetUserRole(c *gin.Context) {
+	var requestBody struct {
+		Role string `json:"role"`
+	}
+
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		Errors.Abort(c, Errors.InvalidBody(err))
+		return
+	}
+	if !isValidRole(requestBody.Role) {
+		Errors.Abort(c, Errors.Field("role", "role must be user, moderator or admin"))
+		return
+	}
+
+	target, err := findTargetUser(c)
+	if err != nil {
+		Errors.Abort(c, err)
+		return
+	}
+
+	// Demoting yourself could leave the site without an admin
+	actor, _ := CurrentUser(c)
+	if target.ID == actor.ID {
+		Errors.Abort(c, Errors.Forbidden("You cannot change your own role"))
+		return
+	}
+
+	if err := Repository.Users().SetRole(c, target.ID, requestBody.Role); err != nil {
+		Errors.Abort(c, Errors.Internal("Error updating user", err))
+		return
+	}
+
+	Logging.FromContext(c).Info("User role changed", "target_user", target.Name, "role", requestBody.Role)
+	c.JSON(http.StatusOK, gin.H{"message": "Role updated successfully", "username": target.Name, "role": requestBody.Role})
+}
@@ -0,0 +1,136 @@
+package Functions
+
+import (
+	"backend/Errors"
+	"backend/Repository"
+	"backend/Schemas"
+	"context"
+	"net/http"
+	"testing"
+)
+
+// adminRouter serves the user administration routes like HTTP.Router
+func adminRouter() http.Handler {
+	router := newTestRouter()
+	router.GET("/profile", AuthRequired, GetProfile)
+	router.POST("/admin/users/:username/ban", AuthRequired, RequirePermission(PermissionBanUser), BanUser)
+	router.DELETE("/admin/users/:username/ban", AuthRequired, RequirePermission(PermissionBanUser), UnbanUser)
+	router.PUT("/admin/users/:username/role", AuthRequired, RequirePermission(PermissionManageUsers), SetUserRole)
+	return router
+}
+
+// createStaff creates alice and bob as users, mod and mod2 as moderators and
+// admin and admin2 as admins, returning their tokens by name
+func createStaff(t *testing.T) map[string]string {
+	t.Helper()
+
+	tokens := map[string]string{}
+	for name, role := range map[string]string{
+		"alice":  Schemas.RoleUser,
+		"bob":    Schemas.RoleUser,
+		"mod":    Schemas.RoleModerator,
+		"mod2":   Schemas.RoleModerator,
+		"admin":  Schemas.RoleAdmin,
+		"admin2": Schemas.RoleAdmin,
+	} {
+		_, tokens[name] = createUser(t, name, func(user *Schemas.User) { user.Role = role })
+	}
+	return tokens
+}
+
+func TestBanUser(t *testing.T) {
+	tests := []struct {
+		name       string
+		actor      string
+		target     string
+		wantStatus int
+		wantCode   Errors.Code
+	}{
+		{"moderator bans user", "mod", "alice", http.StatusOK, ""},
+		{"admin bans moderator", "admin", "mod", http.StatusOK, ""},
+		{"user cannot ban", "bob", "alice", http.StatusForbidden, Errors.CodeForbidden},
+		{"moderator cannot ban moderator", "mod", "mod2", http.StatusForbidden, Errors.CodeForbidden},
+		{"moderator cannot ban admin", "mod", "admin", http.StatusForbidden, Errors.CodeForbidden},
+		{"admin cannot ban admin", "admin", "admin2", http.StatusForbidden, Errors.CodeForbidden},
+		{"nobody bans themselves", "admin", "admin", http.StatusForbidden, Errors.CodeForbidden},
+		{"unknown user", "mod", "nobody", http.StatusNotFound, Errors.CodeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t)
+			tokens := createStaff(t)
+			router := adminRouter()
+
+			recorder := serve(router, http.MethodPost, "/admin/users/"+tt.target+"/ban", "", tokens[tt.actor])
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+			if tt.wantCode != "" {
+				if code := errorCode(t, recorder); code != tt.wantCode {
+					t.Errorf("code = %q, want %q", code, tt.wantCode)
+				}
+				return
+			}
+
+			// The ban takes the tokens of the target with it until lifted
+			if recorder := serve(router, http.MethodGet, "/profile", "", tokens[tt.target]); recorder.Code != http.StatusForbidden {
+				t.Errorf("profile of banned user: status = %d, want 403", recorder.Code)
+			}
+			if recorder := serve(router, http.MethodDelete, "/admin/users/"+tt.target+"/ban", "", tokens[tt.actor]); recorder.Code != http.StatusOK {
+				t.Fatalf("unban: status = %d: %s", recorder.Code, recorder.Body)
+			}
+			if recorder := serve(router, http.MethodGet, "/profile", "", tokens[tt.target]); recorder.Code != http.StatusOK {
+				t.Errorf("profile after unban: status = %d, want 200", recorder.Code)
+			}
+		})
+	}
+}
+
+func TestSetUserRole(t *testing.T) {
+	tests := []struct {
+		name       string
+		actor      string
+		target     string
+		body       string
+		wantStatus int
+		wantCode   Errors.Code
+		wantRole   string
+	}{
+		{"admin promotes", "admin", "alice", `{"role":"moderator"}`, http.StatusOK, "", Schemas.RoleModerator},
+		{"admin demotes", "admin", "mod", `{"role":"user"}`, http.StatusOK, "", Schemas.RoleUser},
+		{"moderator cannot", "mod", "alice", `{"role":"moderator"}`, http.StatusForbidden, Errors.CodeForbidden, Schemas.RoleUser},
+		{"moderator cannot promote themselves", "mod", "mod", `{"role":"admin"}`, http.StatusForbidden, Errors.CodeForbidden, Schemas.RoleModerator},
+		{"admin cannot change own role", "admin", "admin", `{"role":"user"}`, http.StatusForbidden, Errors.CodeForbidden, Schemas.RoleAdmin},
+		{"unknown role", "admin", "alice", `{"role":"root"}`, http.StatusBadRequest, Errors.CodeValidation, Schemas.RoleUser},
+		{"unknown user", "admin", "nobody", `{"role":"user"}`, http.StatusNotFound, Errors.CodeNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t)
+			tokens := createStaff(t)
+
+			recorder := serve(adminRouter(), http.MethodPut, "/admin/users/"+tt.target+"/role", tt.body, tokens[tt.actor])
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+			if tt.wantCode != "" {
+				if code := errorCode(t, recorder); code != tt.wantCode {
+					t.Errorf("code = %q, want %q", code, tt.wantCode)
+				}
+			}
+			if tt.wantRole == "" {
+				return
+			}
+
+			target, err := Repository.Users().FindByUsername(context.Background(), tt.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if target.Role != tt.wantRole {
+				t.Errorf("role = %q, want %q", target.Role, tt.wantRole)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package Functions
+
+import (
+	"backend/Errors"
+	"backend/FunctionsHelper"
+	"backend/Logging"
+	"backend/Repository"
+	"backend/Schemas"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Key under which AuthRequired stores the authenticated Schemas.User
+const userContextKey = "user"
+
+// AuthRequired validates the bearer access token and injects the
+// authenticated user into the context. Requests without a valid token are
+// rejected with 401.
+func AuthRequired(c *gin.Context) {
+	tokenString := bearerToken(c)
+	if tokenString == "" {
+		Errors.Abort(c, Errors.Unauthorized("Authorization token required"))
+		return
+	}
+
+	claims, err := FunctionsHelper.ParseToken(tokenString, FunctionsHelper.AccessTokenType)
+	if err != nil {
+		Errors.Abort(c, Errors.Unauthorized("Invalid or expired token"))
+		return
+	}
+
+	user, err := findUserByID(c, claims.Subject)
+	if err != nil {
+		Errors.Abort(c, Errors.Unauthorized("User no longer exists"))
+		return
+	}
+	if claims.IsRevoked(user) {
+		Errors.Abort(c, Errors.Unauthorized("Token has been revoked"))
+		return
+	}
+	if user.Banned {
+		Errors.Abort(c, Errors.Forbidden("Account is banned"))
+		return
+	}
+
+	c.Set(userContextKey, user)
+	Logging.With(c, "user", user.Name)
+	c.Next()
+}
+
+// OptionalAuth injects the authenticated user like AuthRequired when the
+// request carries a valid access token, and lets anonymous requests through.
+// A bad token is treated as no token so public pages keep working.
+func OptionalAuth(c *gin.Context) {
+	if tokenString := bearerToken(c); tokenString != "" {
+		if claims, err := FunctionsHelper.ParseToken(tokenString, FunctionsHelper.AccessTokenType); err == nil {
+			if user, err := findUserByID(c, claims.Subject); err == nil && !user.Banned && !claims.IsRevoked(user) {
+				c.Set(userContextKey, user)
+				Logging.With(c, "user", user.Name)
+			}
+		}
+	}
+	c.Next()
+}
+
+// bearerToken reads the access token from the Authorization header. Browsers
+// cannot set headers on a WebSocket handshake, so those may pass ?token=
+// instead.
+func bearerToken(c *gin.Context) string {
+	if tokenString, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); found {
+		return tokenString
+	}
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		return c.Query("token")
+	}
+	return ""
+}
+
+// CurrentUser returns the user injected by AuthRequired or OptionalAuth.
+func CurrentUser(c *gin.Context) (Schemas.User, bool) {
+	value, exists := c.Get(userContextKey)
+	if !exists {
+		return Schemas.User{}, false
+	}
+	user, ok := value.(Schemas.User)
+	return user, ok
+}
+
+// RefreshToken exchanges a valid refresh token for a new token pair
+func RefreshToken(c *gin.Context) {
+	var requestBody struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		Errors.Abort(c, Errors.InvalidBody(err))
+		return
+	}
+	if requestBody.RefreshToken == "" {
+		Errors.Abort(c, Errors.Field("refresh_token", "refresh_token is required"))
+		return
+	}
+
+	claims, err := FunctionsHelper.ParseToken(requestBody.RefreshToken, FunctionsHelper.RefreshTokenType)
+	if err != nil {
+		Errors.Abort(c, Errors.Unauthorized("Invalid or expired refresh token"))
+		return
+	}
+
+	user, err := findUserByID(c, claims.Subject)
+	if err != nil {
+		Errors.Abort(c, Errors.Unauthorized("User no longer exists"))
+		return
+	}
+	if claims.IsRevoked(user) {
+		Errors.Abort(c, Errors.Unauthorized("Refresh token has been revoked"))
+		return
+	}
+	if user.Banned {
+		Errors.Abort(c, Errors.Forbidden("Account is banned"))
+		return
+	}
+
+	respondWithTokens(c, user, gin.H{"message": "Token refreshed"})
+}
+
+// respondWithTokens issues a token pair for user and writes it together with
+// the extra fields of body.
+func respondWithTokens(c *gin.Context, user Schemas.User, body gin.H) {
+	accessToken, refreshToken, err := FunctionsHelper.IssueTokenPair(user)
+	if err != nil {
+		Errors.Abort(c, Errors.Internal("Error issuing tokens", err))
+		return
+	}
+
+	body["access_token"] = accessToken
+	body["refresh_token"] = refreshToken
+	body["token_type"] = "Bearer"
+	body["expires_in"] = int(FunctionsHelper.AccessTokenTTL.Seconds())
+	c.JSON(http.StatusOK, body)
+}
+
+func findUserByID(c *gin.Context, id string) (Schemas.User, error) {
+	var user Schemas.User
+
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return user, err
+	}
+
+	return Repository.Users().FindByID(c, objId)
+}
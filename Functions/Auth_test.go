@@ -0,0 +1,196 @@
+package Functions
+
+import (
+	"backend/Errors"
+	"backend/FunctionsHelper"
+	"backend/Repository"
+	"backend/Schemas"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// whoAmI answers with the authenticated user's name, or "" when anonymous
+func whoAmI(c *gin.Context) {
+	user, _ := CurrentUser(c)
+	c.JSON(http.StatusOK, gin.H{"username": user.Name})
+}
+
+func TestAuthRequired(t *testing.T) {
+	setupTest(t)
+	ctx := context.Background()
+
+	alice, aliceToken := createUser(t, "alice", nil)
+	_, aliceRefresh, _ := FunctionsHelper.IssueTokenPair(alice)
+
+	revoked, revokedToken := createUser(t, "revoked", nil)
+	if err := Repository.Users().UpdatePassword(ctx, revoked.ID, "new-hash"); err != nil {
+		t.Fatal(err)
+	}
+
+	banned, bannedToken := createUser(t, "banned", nil)
+	if err := Repository.Users().SetBanned(ctx, banned.ID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Signed correctly, but for a user that does not exist
+	_, ghostToken, _ := FunctionsHelper.IssueTokenPair(Schemas.User{ID: primitive.NewObjectID(), Name: "ghost"})
+
+	router := newTestRouter()
+	router.GET("/me", AuthRequired, whoAmI)
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+		wantCode      Errors.Code
+	}{
+		{"valid access token", "Bearer " + aliceToken, http.StatusOK, ""},
+		{"no header", "", http.StatusUnauthorized, Errors.CodeUnauthorized},
+		{"not a bearer token", "Token " + aliceToken, http.StatusUnauthorized, Errors.CodeUnauthorized},
+		{"malformed token", "Bearer not-a-jwt", http.StatusUnauthorized, Errors.CodeUnauthorized},
+		{"refresh token", "Bearer " + aliceRefresh, http.StatusUnauthorized, Errors.CodeUnauthorized},
+		{"unknown user", "Bearer " + ghostToken, http.StatusUnauthorized, Errors.CodeUnauthorized},
+		{"revoked by password change", "Bearer " + revokedToken, http.StatusUnauthorized, Errors.CodeUnauthorized},
+		{"banned user", "Bearer " + bannedToken, http.StatusForbidden, Errors.CodeForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/me", nil)
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+			if tt.wantCode != "" {
+				if code := errorCode(t, recorder); code != tt.wantCode {
+					t.Errorf("code = %q, want %q", code, tt.wantCode)
+				}
+			}
+		})
+	}
+}
+
+func TestOptionalAuth(t *testing.T) {
+	setupTest(t)
+
+	_, aliceToken := createUser(t, "alice", nil)
+	banned, bannedToken := createUser(t, "banned", nil)
+	if err := Repository.Users().SetBanned(context.Background(), banned.ID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	router := newTestRouter()
+	router.GET("/me", OptionalAuth, whoAmI)
+
+	tests := []struct {
+		name         string
+		token        string
+		wantUsername string
+	}{
+		{"valid token", aliceToken, "alice"},
+		{"no token", "", ""},
+		{"bad token is anonymous", "not-a-jwt", ""},
+		{"banned user is anonymous", bannedToken, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := serve(router, http.MethodGet, "/me", "", tt.token)
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body)
+			}
+			want := `{"username":"` + tt.wantUsername + `"}`
+			if recorder.Body.String() != want {
+				t.Errorf("body = %s, want %s", recorder.Body, want)
+			}
+		})
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		url           string
+		authorization string
+		websocket     bool
+		want          string
+	}{
+		{"header", "/ws", "Bearer abc", false, "abc"},
+		{"header wins on a WebSocket", "/ws?token=query", "Bearer header", true, "header"},
+		{"query on a WebSocket", "/ws?token=abc", "", true, "abc"},
+		{"query ignored without upgrade", "/ws?token=abc", "", false, ""},
+		{"nothing", "/ws", "", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.authorization != "" {
+				c.Request.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.websocket {
+				c.Request.Header.Set("Connection", "Upgrade")
+				c.Request.Header.Set("Upgrade", "websocket")
+			}
+
+			if got := bearerToken(c); got != tt.want {
+				t.Errorf("bearerToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// The author of a post is the token's user, whatever the body claims, and
+// the fake AI moderates it
+func TestCreatePostAuthor(t *testing.T) {
+	ai := setupTest(t)
+	_, aliceToken := createUser(t, "alice", nil)
+
+	router := newTestRouter()
+	router.POST("/post", AuthRequired, CreatePost)
+
+	tests := []struct {
+		name       string
+		token      string
+		body       string
+		wantStatus int
+	}{
+		{"anonymous", "", `{"problem":"How do I start?"}`, http.StatusUnauthorized},
+		{"spoofed username", aliceToken, `{"problem":"How do I start?","username":"mallory","likeCount":99}`, http.StatusOK},
+		{"rejected by AI", aliceToken, `{"problem":"badword everywhere"}`, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := serve(router, http.MethodPost, "/post", tt.body, tt.token)
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+		})
+	}
+
+	page, err := Repository.Posts().FindPage(context.Background(), Repository.PostQuery{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Posts) != 1 {
+		t.Fatalf("stored %d posts, want 1", len(page.Posts))
+	}
+	if post := page.Posts[0]; post.Username != "alice" || post.LikeCount != 0 {
+		t.Errorf("stored post by %q with %d likes, want alice with 0", post.Username, post.LikeCount)
+	}
+	if len(ai.Calls()) == 0 {
+		t.Error("the AI provider was never asked")
+	}
+}
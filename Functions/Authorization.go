@@ -0,0 +1,115 @@
+package Functions
+
+import (
+	"backend/Errors"
+	"backend/Schemas"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Permission is an action that only some roles may perform
+type Permission string
+
+const (
+	PermissionCreateTag        Permission = "create_tag"
+	PermissionLockPost         Permission = "lock_post"
+	PermissionDeleteAnyContent Permission = "delete_any_content"
+	PermissionBanUser          Permission = "ban_user"
+	PermissionManageRooms      Permission = "manage_rooms"
+	PermissionManageUsers      Permission = "manage_users"
+	PermissionManageJobs       Permission = "manage_jobs"
+)
+
+// rolePermissions is the permission matrix. Plain users may only act on
+// their own content, which needs no permission.
+var rolePermissions = map[string][]Permission{
+	Schemas.RoleUser: {},
+	Schemas.RoleModerator: {
+		PermissionCreateTag,
+		PermissionLockPost,
+		PermissionDeleteAnyContent,
+		PermissionBanUser,
+		PermissionManageRooms,
+	},
+	Schemas.RoleAdmin: {
+		PermissionCreateTag,
+		PermissionLockPost,
+		PermissionDeleteAnyContent,
+		PermissionBanUser,
+		PermissionManageRooms,
+		PermissionManageUsers,
+		PermissionManageJobs,
+	},
+}
+
+// roleOf returns the user's role, accounts created before roles are users
+func roleOf(user Schemas.User) string {
+	if user.Role == "" {
+		return Schemas.RoleUser
+	}
+	return user.Role
+}
+
+// isValidRole reports whether role appears in the permission matrix
+func isValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// roleRank orders roles so moderators cannot act against their peers
+func roleRank(role string) int {
+	switch role {
+	case Schemas.RoleAdmin:
+		return 2
+	case Schemas.RoleModerator:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// HasPermission reports whether user's role grants permission
+func HasPermission(user Schemas.User, permission Permission) bool {
+	for _, granted := range rolePermissions[roleOf(user)] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePermission returns a middleware that answers 403 unless the user
+// injected by AuthRequired has permission. It must run after AuthRequired.
+func RequirePermission(permission Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := CurrentUser(c)
+		if !ok {
+			Errors.Abort(c, Errors.Unauthorized("Authentication required"))
+			return
+		}
+		if !HasPermission(user, permission) {
+			Errors.Abort(c, Errors.Forbidden("Missing permission").WithDetail("permission", permission))
+			return
+		}
+		c.Next()
+	}
+}
+
+// canModify reports whether user may change or delete content written by
+// author: the author themselves or someone allowed to delete any content
+func canModify(user Schemas.User, author string) bool {
+	return user.Name == author || HasPermission(user, PermissionDeleteAnyContent)
+}
+
+// authorizeAuthor fails with forbidden unless the current user may modify
+// content written by author
+func authorizeAuthor(c *gin.Context, author string) error {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return Errors.Unauthorized("Authentication required")
+	}
+	if !canModify(user, author) {
+		return Errors.Forbidden("You are not allowed to modify this content")
+	}
+	return nil
+}
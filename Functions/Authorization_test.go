@@ -0,0 +1,94 @@
+package Functions
+
+import (
+	"backend/Errors"
+	"backend/Schemas"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHasPermission(t *testing.T) {
+	all := []Permission{
+		PermissionCreateTag,
+		PermissionLockPost,
+		PermissionDeleteAnyContent,
+		PermissionBanUser,
+		PermissionManageRooms,
+		PermissionManageUsers,
+		PermissionManageJobs,
+	}
+	moderation := map[Permission]bool{
+		PermissionCreateTag:        true,
+		PermissionLockPost:         true,
+		PermissionDeleteAnyContent: true,
+		PermissionBanUser:          true,
+		PermissionManageRooms:      true,
+	}
+
+	tests := []struct {
+		role    string
+		granted func(permission Permission) bool
+	}{
+		{"", func(Permission) bool { return false }}, // Accounts from before roles
+		{Schemas.RoleUser, func(Permission) bool { return false }},
+		{Schemas.RoleModerator, func(permission Permission) bool { return moderation[permission] }},
+		{Schemas.RoleAdmin, func(Permission) bool { return true }},
+		{"superuser", func(Permission) bool { return false }},
+	}
+
+	for _, tt := range tests {
+		for _, permission := range all {
+			user := Schemas.User{Name: "someone", Role: tt.role}
+			if got, want := HasPermission(user, permission), tt.granted(permission); got != want {
+				t.Errorf("HasPermission(role %q, %s) = %v, want %v", tt.role, permission, got, want)
+			}
+		}
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	setupTest(t)
+	tokens := map[string]string{}
+	for name, role := range map[string]string{"alice": Schemas.RoleUser, "legacy": "", "mod": Schemas.RoleModerator, "admin": Schemas.RoleAdmin} {
+		_, tokens[name] = createUser(t, name, func(user *Schemas.User) { user.Role = role })
+	}
+
+	router := newTestRouter()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.POST("/lock", AuthRequired, RequirePermission(PermissionLockPost), ok)
+	router.POST("/jobs", AuthRequired, RequirePermission(PermissionManageJobs), ok)
+	// Without AuthRequired nobody is known
+	router.POST("/unauthenticated", RequirePermission(PermissionLockPost), ok)
+
+	tests := []struct {
+		path       string
+		user       string
+		wantStatus int
+		wantCode   Errors.Code
+	}{
+		{"/lock", "alice", http.StatusForbidden, Errors.CodeForbidden},
+		{"/lock", "legacy", http.StatusForbidden, Errors.CodeForbidden},
+		{"/lock", "mod", http.StatusOK, ""},
+		{"/lock", "admin", http.StatusOK, ""},
+		{"/lock", "", http.StatusUnauthorized, Errors.CodeUnauthorized},
+		{"/jobs", "mod", http.StatusForbidden, Errors.CodeForbidden},
+		{"/jobs", "admin", http.StatusOK, ""},
+		{"/unauthenticated", "admin", http.StatusUnauthorized, Errors.CodeUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+" as "+tt.user, func(t *testing.T) {
+			recorder := serve(router, http.MethodPost, tt.path, "", tokens[tt.user])
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+			if tt.wantCode != "" {
+				if code := errorCode(t, recorder); code != tt.wantCode {
+					t.Errorf("code = %q, want %q", code, tt.wantCode)
+				}
+			}
+		})
+	}
+}
@@ -1,79 +1,100 @@
 package Functions
 
 import (
+	"backend/Errors"
 	"backend/FunctionsHelper"
-	"backend/Mongo"
+	"backend/Logging"
+	"backend/Repository"
 	"backend/Schemas"
-	"context"
-	"log"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func GetAllCommentsForPost(postId string) (comments []Schemas.Comment, err error) {
-	comments = make([]Schemas.Comment, 0)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+func CreateComment(c *gin.Context) {
+	// Only these fields come from the client, the like counter and the ID
+	// are the server's
+	var requestBody struct {
+		PostId      string `json:"post_id"`
+		Description string `json:"description"`
+	}
 
-	cursor, err := Mongo.GetCollection("melje_district").Find(ctx, bson.M{"post_id": postId})
-	if err != nil {
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		Errors.Abort(c, Errors.InvalidBody(err))
 		return
 	}
-	defer cursor.Close(ctx)
+	comment := Schemas.Comment{PostId: requestBody.PostId, Description: requestBody.Description}
 
-	for cursor.Next(ctx) {
-		var comment Schemas.Comment
-		err := cursor.Decode(&comment)
-		if err == nil {
-			comments = append(comments, comment)
-		}
+	// The author is always the authenticated user, never the request body
+	user, ok := CurrentUser(c)
+	if !ok {
+		Errors.Abort(c, Errors.Unauthorized("Authentication required"))
+		return
 	}
-	return
-}
+	comment.Username = user.Name
 
-func CreateComment(c *gin.Context) {
-	var comment Schemas.Comment
+	// Validate the referenced post
+	postId, err := primitive.ObjectIDFromHex(comment.PostId)
+	if err != nil {
+		Errors.Abort(c, Errors.Field("post_id", "Invalid post_id"))
+		return
+	}
 
-	// Bind the JSON body to the comment struct
-	if err := c.ShouldBindJSON(&comment); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request"})
+	// Locked threads take no new comments
+	post, err := Repository.Posts().FindByID(c, postId)
+	if errors.Is(err, Repository.ErrNotFound) {
+		Errors.Abort(c, Errors.NotFound("Post not found"))
+		return
+	}
+	if err != nil {
+		Errors.Abort(c, Errors.Internal("Error loading post", err))
+		return
+	}
+	if err := lockedError(post); err != nil {
+		Errors.Abort(c, err)
 		return
 	}
 
 	// Validate the comment description
 	if comment.Description == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Comment description cannot be empty"})
+		Errors.Abort(c, Errors.Field("description", "Comment description cannot be empty"))
 		return
 	}
 
 	// Validate the comment with AI
-	aiResponseV, err := FunctionsHelper.CallAIService(comment.Description, 1, "You are a bot that checks if the post is appropriate or not. By appropriate it is meant there are bad words. If it is appropriate return 1; else return 0.")
+	appropriate, err := FunctionsHelper.IsContentAppropriate(c, comment.Description)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Error generating AI response"})
+		Errors.Abort(c, Errors.AIUnavailable(err))
 		return
 	}
 
 	// Check AI's approval
-	if aiResponseV == "0" { // Assuming aiResponseV is a string
-		log.Println("AI Response not approved: AI returned 0")
-		c.JSON(http.StatusForbidden, gin.H{"message": "Not approved by AI"}) // HTTP 403 Forbidden
+	if !appropriate {
+		Logging.FromContext(c).Info("Comment rejected by AI moderation", "post_id", comment.PostId, "ai_provider", FunctionsHelper.GetAIProvider().Name())
+		Errors.Abort(c, Errors.Forbidden("Not approved by AI"))
 		return
 	}
 
 	// Set additional fields in the comment object
-	comment.Date = time.Now().Format("2006-01-02")
+	now := time.Now().UTC()
+	comment.Date = now.Format("2006-01-02")
+	comment.CreatedAt = now
 
 	// Insert the comment into the MongoDB collection
-	_, insertErr := Mongo.GetCollection("melje_district").InsertOne(c, comment)
+	_, insertErr := Repository.Comments().Create(c, comment)
 	if insertErr != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Error creating comment"})
+		Errors.Abort(c, Errors.Internal("Error creating comment", insertErr))
 		return
 	}
 
+	// Keep the post's comment counter in sync for sorting
+	if err := Repository.Posts().IncrementCommentCount(c, postId, 1); err != nil {
+		Logging.FromContext(c).Error("Error updating comment count", "post_id", comment.PostId, "error", err)
+	}
+
 	// Respond with success
 	c.JSON(http.StatusOK, gin.H{"message": "Comment added successfully"})
 }
@@ -81,64 +102,52 @@ func CreateComment(c *gin.Context) {
 func DeleteComment(c *gin.Context) {
 	commentId := c.Query("comment_id")
 	if commentId == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "comment_id is required"})
+		Errors.Abort(c, Errors.Field("comment_id", "comment_id is required"))
 		return
 	}
 
-	objId, _ := primitive.ObjectIDFromHex(commentId)
-
-	_, err := Mongo.GetCollection("melje_district").DeleteOne(c, bson.M{"_id": objId})
+	objId, err := primitive.ObjectIDFromHex(commentId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Error deleting comment"})
+		Errors.Abort(c, Errors.Field("comment_id", "Invalid comment_id"))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
-}
-
-func LikeComment(c *gin.Context) {
-	var requestBody struct {
-		CommentID string `json:"comment_id"`
+	// Look up the comment first to check its author and update the post's counter
+	comment, err := Repository.Comments().FindByID(c, objId)
+	if errors.Is(err, Repository.ErrNotFound) {
+		Errors.Abort(c, Errors.NotFound("Comment not found"))
+		return
 	}
-
-	// Bind JSON body to the requestBody struct
-	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request body"})
+	if err != nil {
+		Errors.Abort(c, Errors.Internal("Error deleting comment", err))
 		return
 	}
 
-	// Validate comment_id
-	if requestBody.CommentID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "comment_id is required"})
+	// Only the author or a moderator may delete a comment
+	if err := authorizeAuthor(c, comment.Username); err != nil {
+		Errors.Abort(c, err)
 		return
 	}
 
-	// Convert comment_id to ObjectID
-	commentId, err := primitive.ObjectIDFromHex(requestBody.CommentID)
+	deleted, err := Repository.Comments().Delete(c, objId)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid comment_id"})
+		Errors.Abort(c, Errors.Internal("Error deleting comment", err))
 		return
 	}
-
-	// Access the collection
-	collection := Mongo.GetCollection("melje_district") // Assuming "comments" collection, change if needed
-
-	// Increment the LikeCount by 1
-	update := bson.M{"$inc": bson.M{"likeCount": 1}}
-	filter := bson.M{"_id": commentId}
-
-	result, err := collection.UpdateOne(c, filter, update)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to update comment", "error": err.Error()})
+	if !deleted {
+		Errors.Abort(c, Errors.NotFound("Comment not found"))
 		return
 	}
 
-	// Check if a document was updated
-	if result.MatchedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"message": "Comment not found"})
-		return
+	if postId, err := primitive.ObjectIDFromHex(comment.PostId); err == nil {
+		if err := Repository.Posts().IncrementCommentCount(c, postId, -1); err != nil {
+			Logging.FromContext(c).Error("Error updating comment count", "post_id", comment.PostId, "error", err)
+		}
+	}
+
+	if _, err := Repository.Likes().DeleteByTarget(c, Schemas.LikeTargetComment, []primitive.ObjectID{objId}); err != nil {
+		Logging.FromContext(c).Error("Error deleting likes of comment", "comment_id", commentId, "error", err)
 	}
 
-	// Return success response
-	c.JSON(http.StatusOK, gin.H{"message": "Comment liked successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
 }
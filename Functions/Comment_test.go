@@ -0,0 +1,148 @@
+package Functions
+
+import (
+	"backend/Errors"
+	"backend/Repository"
+	"backend/Schemas"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDeleteComment(t *testing.T) {
+	tests := []struct {
+		name       string
+		deleter    string // Empty deletes anonymously
+		wantStatus int
+		wantCode   Errors.Code
+	}{
+		{"author", "bob", http.StatusOK, ""},
+		{"post author", "alice", http.StatusForbidden, Errors.CodeForbidden},
+		{"moderator", "mod", http.StatusOK, ""},
+		{"anonymous", "", http.StatusUnauthorized, Errors.CodeUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t)
+			ctx := context.Background()
+
+			tokens := map[string]string{}
+			for name, role := range map[string]string{"alice": Schemas.RoleUser, "bob": Schemas.RoleUser, "mod": Schemas.RoleModerator} {
+				_, tokens[name] = createUser(t, name, func(user *Schemas.User) { user.Role = role })
+			}
+			postID, err := Repository.Posts().Create(ctx, Schemas.Post{Problem: "Question", Username: "alice", CommentCount: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			commentID, err := Repository.Comments().Create(ctx, Schemas.Comment{Description: "Answer", Username: "bob", PostId: postID.Hex()})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			router := newTestRouter()
+			router.DELETE("/comment", AuthRequired, DeleteComment)
+
+			recorder := serve(router, http.MethodDelete, "/comment?comment_id="+commentID.Hex(), "", tokens[tt.deleter])
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+			if tt.wantCode != "" {
+				if code := errorCode(t, recorder); code != tt.wantCode {
+					t.Errorf("code = %q, want %q", code, tt.wantCode)
+				}
+			}
+
+			deleted := tt.wantStatus == http.StatusOK
+			if _, err := Repository.Comments().FindByID(ctx, commentID); errors.Is(err, Repository.ErrNotFound) != deleted {
+				t.Errorf("finding the comment: %v, want deleted %v", err, deleted)
+			}
+			post, err := Repository.Posts().FindByID(ctx, postID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if wantCount := map[bool]int{true: 0, false: 1}[deleted]; post.CommentCount != wantCount {
+				t.Errorf("comment count = %d, want %d", post.CommentCount, wantCount)
+			}
+		})
+	}
+}
+
+func TestCreateCommentOnLockedPost(t *testing.T) {
+	tests := []struct {
+		name       string
+		lock       bool
+		unlock     bool
+		missing    bool
+		wantStatus int
+		wantCode   Errors.Code
+	}{
+		{"open post", false, false, false, http.StatusOK, ""},
+		{"locked post", true, false, false, http.StatusLocked, Errors.CodeLocked},
+		{"unlocked again", true, true, false, http.StatusOK, ""},
+		{"missing post", false, false, true, http.StatusNotFound, Errors.CodeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ai := setupTest(t)
+			ctx := context.Background()
+
+			_, token := createUser(t, "alice", nil)
+			postID, err := Repository.Posts().Create(ctx, Schemas.Post{Problem: "Question", Username: "alice"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.lock {
+				if _, err := Repository.Posts().Lock(ctx, postID, "Off topic", "mod", time.Now().UTC()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.unlock {
+				if _, err := Repository.Posts().Unlock(ctx, postID); err != nil {
+					t.Fatal(err)
+				}
+			}
+			target := postID
+			if tt.missing {
+				target = primitive.NewObjectID()
+			}
+
+			router := newTestRouter()
+			router.POST("/comment", AuthRequired, CreateComment)
+
+			body := fmt.Sprintf(`{"post_id":%q,"description":"An answer"}`, target.Hex())
+			recorder := serve(router, http.MethodPost, "/comment", body, token)
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+			if tt.wantCode != "" {
+				errBody := errorBody(t, recorder)
+				if errBody.Code != tt.wantCode {
+					t.Errorf("code = %q, want %q", errBody.Code, tt.wantCode)
+				}
+				if tt.wantCode == Errors.CodeLocked && errBody.Details["lock_reason"] != "Off topic" {
+					t.Errorf("details = %v, want the lock reason", errBody.Details)
+				}
+			}
+
+			created := tt.wantStatus == http.StatusOK
+			comments, err := Repository.Comments().FindByPostID(ctx, postID.Hex())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (len(comments) == 1) != created {
+				t.Errorf("stored %d comments, want created %v", len(comments), created)
+			}
+			// A rejected comment never reaches the AI
+			if (len(ai.Calls()) > 0) != created {
+				t.Errorf("AI was called %d times, want called %v", len(ai.Calls()), created)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package Functions
+
+import "backend/Config"
+
+// appConfig is the configuration the handlers read, set by Configure
+var appConfig = Config.Default()
+
+// Configure hands the handlers their configuration. It must be called before
+// the routes are set up.
+func Configure(cfg *Config.Config) {
+	appConfig = cfg
+}
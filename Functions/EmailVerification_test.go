@@ -0,0 +1,80 @@
+package Functions
+
+import (
+	"backend/Errors"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestEmailVerification(t *testing.T) {
+	setupTest(t)
+	mailer := useFileMailer(t)
+
+	router := newTestRouter()
+	router.POST("/register", Register)
+	router.POST("/login", Login)
+	router.GET("/verify-email", VerifyEmail)
+	router.POST("/verify-email/resend", AuthRequired, ResendVerificationEmail)
+	router.POST("/post", AuthRequired, RequireVerified, CreatePost)
+	router.POST("/comment", AuthRequired, RequireVerified, CreateComment)
+	router.POST("/create_room", AuthRequired, RequireVerified, CreateRoom)
+	router.GET("/profile", AuthRequired, GetProfile)
+
+	if recorder := serve(router, http.MethodPost, "/register", `{"username":"alice","email":"alice@example.com","password":"secret123"}`, ""); recorder.Code != http.StatusOK {
+		t.Fatalf("register: status = %d: %s", recorder.Code, recorder.Body)
+	}
+	recorder := serve(router, http.MethodPost, "/login", `{"username":"alice","password":"secret123"}`, "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("login: status = %d: %s", recorder.Code, recorder.Body)
+	}
+	var tokens struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &tokens); err != nil {
+		t.Fatal(err)
+	}
+	if recorder := serve(router, http.MethodPost, "/verify-email/resend", "", tokens.AccessToken); recorder.Code != http.StatusOK {
+		t.Fatalf("resend: status = %d: %s", recorder.Code, recorder.Body)
+	}
+	sent := mailer.Sent()
+	if len(sent) != 2 {
+		t.Fatalf("sent %d mails, want the registration and the resent one", len(sent))
+	}
+	firstToken, newestToken := mailedToken(t, sent[0]), mailedToken(t, sent[1])
+
+	steps := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantCode   Errors.Code
+	}{
+		{"unverified may read", http.MethodGet, "/profile", "", http.StatusOK, ""},
+		{"unverified may not post", http.MethodPost, "/post", `{"problem":"How do I verify?"}`, http.StatusForbidden, Errors.CodeForbidden},
+		{"unverified may not comment", http.MethodPost, "/comment", `{"post_id":"000000000000000000000001","description":"Hi"}`, http.StatusForbidden, Errors.CodeForbidden},
+		{"unverified may not create rooms", http.MethodPost, "/create_room", `{"room_name":"general"}`, http.StatusForbidden, Errors.CodeForbidden},
+		{"resending replaced the first link", http.MethodGet, "/verify-email?token=" + url.QueryEscape(firstToken), "", http.StatusBadRequest, Errors.CodeValidation},
+		{"verifies", http.MethodGet, "/verify-email?token=" + url.QueryEscape(newestToken), "", http.StatusOK, ""},
+		{"links work once", http.MethodGet, "/verify-email?token=" + url.QueryEscape(newestToken), "", http.StatusBadRequest, Errors.CodeValidation},
+		{"verified may post", http.MethodPost, "/post", `{"problem":"How do I verify?"}`, http.StatusOK, ""},
+		{"verified may create rooms", http.MethodPost, "/create_room", `{"room_name":"general"}`, http.StatusCreated, ""},
+		{"nothing left to resend", http.MethodPost, "/verify-email/resend", "", http.StatusConflict, Errors.CodeConflict},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			recorder := serve(router, step.method, step.path, step.body, tokens.AccessToken)
+			if recorder.Code != step.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, step.wantStatus, recorder.Body)
+			}
+			if step.wantCode != "" {
+				if code := errorCode(t, recorder); code != step.wantCode {
+					t.Errorf("code = %q, want %q", code, step.wantCode)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package Functions
+
+import (
+	"backend/FunctionsHelper"
+	"backend/Mongo"
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Time each readiness check may take
+const readinessCheckTimeout = 2 * time.Second
+
+// aiCheck remembers the last AI provider check, every check is a paid call
+var aiCheck struct {
+	mu        sync.Mutex
+	checkedAt time.Time
+	err       error
+}
+
+// checkAI asks the AI provider for a one token answer, at most once per
+// configured interval. Concurrent callers wait for the same check.
+func checkAI(ctx context.Context) error {
+	aiCheck.mu.Lock()
+	defer aiCheck.mu.Unlock()
+
+	if !aiCheck.checkedAt.IsZero() && time.Since(aiCheck.checkedAt) < time.Duration(appConfig.Health.AICheckInterval) {
+		return aiCheck.err
+	}
+
+	_, err := FunctionsHelper.GetAIProvider().Complete(ctx, "Answer with 1.", "ping", 1)
+	aiCheck.checkedAt = time.Now()
+	aiCheck.err = err
+	return err
+}
+
+// ready is true while the server should receive traffic. main sets it once
+// everything is started and clears it before draining.
+var ready atomic.Bool
+
+// SetReady flips the readiness reported by Readiness
+func SetReady(value bool) {
+	ready.Store(value)
+}
+
+// Liveness answers 200 as long as the process serves requests
+func Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readiness answers 200 while the server takes traffic and 503 while it is
+// starting or draining, or when MongoDB (or the AI provider, if configured)
+// does not answer, so load balancers send requests elsewhere. The AI answer
+// is cached, see checkAI.
+func Readiness(c *gin.Context) {
+	checks := gin.H{}
+	healthy := true
+	check := func(name string, probe func(ctx context.Context) error) {
+		ctx, cancel := context.WithTimeout(c, readinessCheckTimeout)
+		defer cancel()
+
+		if err := probe(ctx); err != nil {
+			checks[name] = err.Error()
+			healthy = false
+			return
+		}
+		checks[name] = "ok"
+	}
+
+	if appConfig.Storage.Backend == "mongo" {
+		check("mongo", Mongo.Ping)
+	}
+	if appConfig.Health.CheckAI {
+		check("ai", checkAI)
+	}
+
+	switch {
+	case !ready.Load():
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "checks": checks})
+	case !healthy:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unhealthy", "checks": checks})
+	default:
+		c.JSON(http.StatusOK, gin.H{"status": "ready", "checks": checks})
+	}
+}
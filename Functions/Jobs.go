@@ -0,0 +1,62 @@
+package Functions
+
+import (
+	"backend/Errors"
+	cronjobs "backend/cronJobs"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetJobs lists the scheduled jobs with their next and last run
+func GetJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"jobs": cronjobs.Default.Status(c)})
+}
+
+// RunJob starts a job in the background and responds with the ID its run
+// will be recorded under
+func RunJob(c *gin.Context) {
+	run, err := cronjobs.Default.RunNow(c.Param("name"))
+	if errors.Is(err, cronjobs.ErrUnknownJob) {
+		Errors.Abort(c, Errors.NotFound("Job not found"))
+		return
+	}
+	if errors.Is(err, cronjobs.ErrJobRunning) {
+		Errors.Abort(c, Errors.Conflict("Job is already running"))
+		return
+	}
+	if err != nil {
+		Errors.Abort(c, Errors.Internal("Error starting job", err))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Job started", "run_id": run.ID.Hex(), "started_at": run.StartedAt})
+}
+
+// PreviewLockOldPosts reports which posts the lock_old_posts job would lock
+// right now without changing anything
+func PreviewLockOldPosts(c *gin.Context) {
+	policy := cronjobs.CurrentLockPolicy()
+
+	candidates, checked, err := cronjobs.FindLockCandidates(c, policy, time.Now())
+	if err != nil {
+		Errors.Abort(c, Errors.Internal("Error finding posts to lock", err))
+		return
+	}
+	if candidates == nil {
+		candidates = []cronjobs.LockCandidate{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"policy": gin.H{
+			"inactivity_days":   int(policy.InactivityWindow.Hours() / 24),
+			"min_post_age_days": int(policy.MinPostAge.Hours() / 24),
+			"exempt_pinned":     policy.ExemptPinned,
+			"exempt_unanswered": policy.ExemptUnanswered,
+		},
+		"checked": checked,
+		"posts":   candidates,
+	})
+}
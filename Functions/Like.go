@@ -0,0 +1,215 @@
+package Functions
+
+import (
+	"backend/Errors"
+	"backend/Repository"
+	"backend/Schemas"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// likeTargetID reads the ID of the liked content from the query string or,
+// failing that, from the JSON body
+func likeTargetID(c *gin.Context, field string) (primitive.ObjectID, error) {
+	value := c.Query(field)
+	if value == "" {
+		var requestBody map[string]string
+		if err := c.ShouldBindJSON(&requestBody); err != nil {
+			return primitive.NilObjectID, Errors.InvalidBody(err)
+		}
+		value = requestBody[field]
+	}
+
+	if value == "" {
+		return primitive.NilObjectID, Errors.Field(field, field+" is required")
+	}
+
+	id, err := primitive.ObjectIDFromHex(value)
+	if err != nil {
+		return primitive.NilObjectID, Errors.Field(field, "Invalid "+field)
+	}
+	return id, nil
+}
+
+// loadOpenPost loads a post that may still receive activity, failing with
+// not found or locked otherwise
+func loadOpenPost(c *gin.Context, id primitive.ObjectID) (Schemas.Post, error) {
+	post, err := Repository.Posts().FindByID(c, id)
+	if errors.Is(err, Repository.ErrNotFound) {
+		return Schemas.Post{}, Errors.NotFound("Post not found")
+	}
+	if err != nil {
+		return Schemas.Post{}, Errors.Internal("Error loading post", err)
+	}
+	if err := lockedError(post); err != nil {
+		return Schemas.Post{}, err
+	}
+	return post, nil
+}
+
+// loadOpenComment loads a comment whose post may still receive activity
+func loadOpenComment(c *gin.Context, id primitive.ObjectID) (Schemas.Comment, error) {
+	comment, err := Repository.Comments().FindByID(c, id)
+	if errors.Is(err, Repository.ErrNotFound) {
+		return Schemas.Comment{}, Errors.NotFound("Comment not found")
+	}
+	if err != nil {
+		return Schemas.Comment{}, Errors.Internal("Error loading comment", err)
+	}
+
+	if postId, err := primitive.ObjectIDFromHex(comment.PostId); err == nil {
+		post, err := Repository.Posts().FindByID(c, postId)
+		if err != nil && !errors.Is(err, Repository.ErrNotFound) {
+			return Schemas.Comment{}, Errors.Internal("Error loading post", err)
+		}
+		if err == nil {
+			if err := lockedError(post); err != nil {
+				return Schemas.Comment{}, err
+			}
+		}
+	}
+	return comment, nil
+}
+
+// setLike likes or unlikes the target for the current user. Repeating either
+// is harmless, the response always carries the resulting state.
+func setLike(c *gin.Context, targetType string, targetID primitive.ObjectID, like bool) (bool, error) {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return false, errors.New("no authenticated user")
+	}
+
+	if like {
+		return Repository.Likes().Like(c, user.ID, targetType, targetID)
+	}
+	return Repository.Likes().Unlike(c, user.ID, targetType, targetID)
+}
+
+// LikePost likes a post once per user, body {"post_id"}
+func LikePost(c *gin.Context) {
+	changePostLike(c, true)
+}
+
+// UnlikePost takes back a like, ?post_id=
+func UnlikePost(c *gin.Context) {
+	changePostLike(c, false)
+}
+
+func changePostLike(c *gin.Context, like bool) {
+	postId, err := likeTargetID(c, "post_id")
+	if err != nil {
+		Errors.Abort(c, err)
+		return
+	}
+	if _, err := loadOpenPost(c, postId); err != nil {
+		Errors.Abort(c, err)
+		return
+	}
+
+	changed, err := setLike(c, Schemas.LikeTargetPost, postId, like)
+	if errors.Is(err, Repository.ErrNotFound) {
+		// Deleted since it was loaded
+		Errors.Abort(c, Errors.NotFound("Post not found"))
+		return
+	}
+	if err != nil {
+		Errors.Abort(c, Errors.Internal("Failed to update post", err))
+		return
+	}
+
+	// Read the counter back so the client can show it
+	post, err := Repository.Posts().FindByID(c, postId)
+	if err != nil {
+		Errors.Abort(c, Errors.Internal("Failed to update post", err))
+		return
+	}
+
+	message := "Post liked successfully"
+	if !like {
+		message = "Post unliked successfully"
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message, "liked_by_me": like, "changed": changed, "likeCount": post.LikeCount})
+}
+
+// LikeComment likes a comment once per user, body {"comment_id"}
+func LikeComment(c *gin.Context) {
+	changeCommentLike(c, true)
+}
+
+// UnlikeComment takes back a like, ?comment_id=
+func UnlikeComment(c *gin.Context) {
+	changeCommentLike(c, false)
+}
+
+func changeCommentLike(c *gin.Context, like bool) {
+	commentId, err := likeTargetID(c, "comment_id")
+	if err != nil {
+		Errors.Abort(c, err)
+		return
+	}
+	if _, err := loadOpenComment(c, commentId); err != nil {
+		Errors.Abort(c, err)
+		return
+	}
+
+	changed, err := setLike(c, Schemas.LikeTargetComment, commentId, like)
+	if errors.Is(err, Repository.ErrNotFound) {
+		// Deleted since it was loaded
+		Errors.Abort(c, Errors.NotFound("Comment not found"))
+		return
+	}
+	if err != nil {
+		Errors.Abort(c, Errors.Internal("Failed to update comment", err))
+		return
+	}
+
+	comment, err := Repository.Comments().FindByID(c, commentId)
+	if err != nil {
+		Errors.Abort(c, Errors.Internal("Failed to update comment", err))
+		return
+	}
+
+	message := "Comment liked successfully"
+	if !like {
+		message = "Comment unliked successfully"
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message, "liked_by_me": like, "changed": changed, "likeCount": comment.LikeCount})
+}
+
+// markLikedByMe sets LikedByMe on posts and their loaded comments for the
+// authenticated user, if any. Anonymous requests leave every flag false.
+func markLikedByMe(c *gin.Context, posts []Schemas.Post) error {
+	user, ok := CurrentUser(c)
+	if !ok || len(posts) == 0 {
+		return nil
+	}
+
+	postIDs := make([]primitive.ObjectID, 0, len(posts))
+	var commentIDs []primitive.ObjectID
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+		for _, comment := range post.Comments {
+			commentIDs = append(commentIDs, comment.ID)
+		}
+	}
+
+	likedPosts, err := Repository.Likes().LikedTargets(c, user.ID, Schemas.LikeTargetPost, postIDs)
+	if err != nil {
+		return err
+	}
+	likedComments, err := Repository.Likes().LikedTargets(c, user.ID, Schemas.LikeTargetComment, commentIDs)
+	if err != nil {
+		return err
+	}
+
+	for i := range posts {
+		posts[i].LikedByMe = likedPosts[posts[i].ID]
+		for j := range posts[i].Comments {
+			posts[i].Comments[j].LikedByMe = likedComments[posts[i].Comments[j].ID]
+		}
+	}
+	return nil
+}
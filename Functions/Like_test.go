@@ -0,0 +1,139 @@
+package Functions
+
+import (
+	"backend/Errors"
+	"backend/Repository"
+	"backend/Schemas"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestLikePost(t *testing.T) {
+	setupTest(t)
+	ctx := context.Background()
+
+	_, aliceToken := createUser(t, "alice", nil)
+	_, bobToken := createUser(t, "bob", nil)
+	postID, err := Repository.Posts().Create(ctx, Schemas.Post{Problem: "Like me", Username: "carol"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commentID, err := Repository.Comments().Create(ctx, Schemas.Comment{Description: "And me", Username: "carol", PostId: postID.Hex()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := newTestRouter()
+	router.GET("/post", OptionalAuth, GetPost)
+	router.POST("/post/like", AuthRequired, LikePost)
+	router.DELETE("/post/like", AuthRequired, UnlikePost)
+	router.POST("/comment/like", AuthRequired, LikeComment)
+
+	postBody := `{"post_id":"` + postID.Hex() + `"}`
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		body        string
+		token       string
+		wantChanged bool
+		wantCount   int
+	}{
+		{"like", http.MethodPost, "/post/like", postBody, aliceToken, true, 1},
+		{"like again", http.MethodPost, "/post/like", postBody, aliceToken, false, 1},
+		{"another user likes", http.MethodPost, "/post/like", postBody, bobToken, true, 2},
+		{"unlike", http.MethodDelete, "/post/like?post_id=" + postID.Hex(), "", bobToken, true, 1},
+		{"unlike again", http.MethodDelete, "/post/like?post_id=" + postID.Hex(), "", bobToken, false, 1},
+		{"like the comment", http.MethodPost, "/comment/like", `{"comment_id":"` + commentID.Hex() + `"}`, aliceToken, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := serve(router, tt.method, tt.path, tt.body, tt.token)
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", recorder.Code, recorder.Body)
+			}
+
+			var response struct {
+				Changed   bool `json:"changed"`
+				LikeCount int  `json:"likeCount"`
+			}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if response.Changed != tt.wantChanged || response.LikeCount != tt.wantCount {
+				t.Errorf("changed %v, likeCount %d, want %v, %d", response.Changed, response.LikeCount, tt.wantChanged, tt.wantCount)
+			}
+		})
+	}
+
+	// liked_by_me is per user and false without a token
+	likedByMe := []struct {
+		name            string
+		token           string
+		wantPostLiked   bool
+		wantCommentLike bool
+	}{
+		{"alice", aliceToken, true, true},
+		{"bob", bobToken, false, false},
+		{"anonymous", "", false, false},
+	}
+	for _, tt := range likedByMe {
+		t.Run("liked_by_me for "+tt.name, func(t *testing.T) {
+			recorder := serve(router, http.MethodGet, "/post?post_id="+postID.Hex(), "", tt.token)
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", recorder.Code, recorder.Body)
+			}
+
+			var post Schemas.Post
+			if err := json.Unmarshal(recorder.Body.Bytes(), &post); err != nil {
+				t.Fatal(err)
+			}
+			if post.LikedByMe != tt.wantPostLiked || len(post.Comments) != 1 || post.Comments[0].LikedByMe != tt.wantCommentLike {
+				t.Errorf("post liked %v, comments %+v, want post %v and comment %v", post.LikedByMe, post.Comments, tt.wantPostLiked, tt.wantCommentLike)
+			}
+		})
+	}
+}
+
+func TestLikeRejects(t *testing.T) {
+	setupTest(t)
+	ctx := context.Background()
+
+	_, token := createUser(t, "alice", nil)
+	lockedID, err := Repository.Posts().Create(ctx, Schemas.Post{Problem: "Locked", Locked: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := newTestRouter()
+	router.POST("/post/like", AuthRequired, LikePost)
+
+	tests := []struct {
+		name       string
+		body       string
+		token      string
+		wantStatus int
+		wantCode   Errors.Code
+	}{
+		{"anonymous", `{"post_id":"` + lockedID.Hex() + `"}`, "", http.StatusUnauthorized, Errors.CodeUnauthorized},
+		{"missing id", `{}`, token, http.StatusBadRequest, Errors.CodeValidation},
+		{"invalid id", `{"post_id":"nope"}`, token, http.StatusBadRequest, Errors.CodeValidation},
+		{"unknown post", `{"post_id":"000000000000000000000001"}`, token, http.StatusNotFound, Errors.CodeNotFound},
+		{"locked post", `{"post_id":"` + lockedID.Hex() + `"}`, token, http.StatusLocked, Errors.CodeLocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := serve(router, http.MethodPost, "/post/like", tt.body, tt.token)
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+			if code := errorCode(t, recorder); code != tt.wantCode {
+				t.Errorf("code = %q, want %q", code, tt.wantCode)
+			}
+		})
+	}
+}
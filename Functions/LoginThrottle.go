@@ -0,0 +1,144 @@
+package Functions
+
+import (
+	"backend/Config"
+	"backend/Errors"
+	"backend/Logging"
+	"backend/Repository"
+	"backend/Schemas"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// throttleRule says after how many recent failures a key has to wait and
+// after how many it is locked out
+type throttleRule struct {
+	BackoffAfter int
+	LockoutAfter int
+}
+
+// loginThrottlePolicy limits failed logins per username and per IP address,
+// see Config.LoginThrottle
+type loginThrottlePolicy struct {
+	Username  throttleRule
+	IP        throttleRule
+	BaseDelay time.Duration
+	Lockout   time.Duration
+	Window    time.Duration
+}
+
+// newLoginThrottlePolicy converts the configured limits
+func newLoginThrottlePolicy(cfg Config.LoginThrottle) loginThrottlePolicy {
+	return loginThrottlePolicy{
+		Username:  throttleRule{BackoffAfter: cfg.BackoffAfter, LockoutAfter: cfg.LockoutAfter},
+		IP:        throttleRule{BackoffAfter: cfg.IPBackoffAfter, LockoutAfter: cfg.IPLockoutAfter},
+		BaseDelay: time.Duration(cfg.BaseDelay),
+		Lockout:   time.Duration(cfg.Lockout),
+		Window:    time.Duration(cfg.Window),
+	}
+}
+
+// delay returns how long after its last failure a key with failures has to wait
+func (p loginThrottlePolicy) delay(rule throttleRule, failures int) time.Duration {
+	switch {
+	case failures >= rule.LockoutAfter:
+		return p.Lockout
+	case failures < rule.BackoffAfter:
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := rule.BackoffAfter; i < failures && delay < p.Lockout; i++ {
+		delay *= 2
+	}
+	if delay > p.Lockout {
+		delay = p.Lockout
+	}
+	return delay
+}
+
+// loginThrottleKey is one counter a login attempt is charged to
+type loginThrottleKey struct {
+	Key  string
+	Rule throttleRule
+}
+
+// keys returns the counters for an attempt on username. Usernames
+// are case-insensitive, so are their counters. The IP is the connection's
+// unless it comes from a trusted proxy, so a forged X-Forwarded-For does not
+// get a fresh counter.
+func (p loginThrottlePolicy) keys(c *gin.Context, username string) []loginThrottleKey {
+	return []loginThrottleKey{
+		{Key: "user:" + strings.ToLower(username), Rule: p.Username},
+		{Key: "ip:" + c.ClientIP(), Rule: p.IP},
+	}
+}
+
+// retryAfter returns how long the attempt has to wait, 0 when it may proceed.
+// The throttle fails open: when the store is unavailable logins still work.
+func (p loginThrottlePolicy) retryAfter(c *gin.Context, keys []loginThrottleKey, now time.Time) time.Duration {
+	var wait time.Duration
+	for _, key := range keys {
+		attempts, err := Repository.LoginAttempts().Get(c, key.Key)
+		if errors.Is(err, Repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			Logging.FromContext(c).Error("Error loading login attempts", "key", key.Key, "error", err)
+			continue
+		}
+
+		if remaining := attempts.LastFailure.Add(p.delay(key.Rule, attempts.Failures)).Sub(now); remaining > wait {
+			wait = remaining
+		}
+	}
+	return wait
+}
+
+// recordFailure charges a failed attempt on user to every key, audits it and
+// returns how long the next attempt has to wait. user may only carry the
+// attempted name when no such account exists.
+func (p loginThrottlePolicy) recordFailure(c *gin.Context, user Schemas.User, keys []loginThrottleKey, now time.Time) time.Duration {
+	recordSecurityEvent(c, user, Schemas.SecurityEventLoginFailed)
+
+	var wait time.Duration
+	for _, key := range keys {
+		attempts, err := Repository.LoginAttempts().RecordFailure(c, key.Key, now, p.Window)
+		if err != nil {
+			Logging.FromContext(c).Error("Error recording login failure", "key", key.Key, "error", err)
+			continue
+		}
+
+		if attempts.Failures == key.Rule.LockoutAfter {
+			Logging.FromContext(c).Warn("Login locked out", "key", key.Key, "failures", attempts.Failures)
+			if key.Rule == p.Username {
+				recordSecurityEvent(c, user, Schemas.SecurityEventAccountLocked)
+			}
+		}
+		if delay := p.delay(key.Rule, attempts.Failures); delay > wait {
+			wait = delay
+		}
+	}
+	return wait
+}
+
+// resetUsername forgets the failures of username after a successful login.
+// The IP counter is kept so one valid account cannot clear it for a spray.
+func (p loginThrottlePolicy) resetUsername(c *gin.Context, keys []loginThrottleKey) {
+	if err := Repository.LoginAttempts().Reset(c, keys[0].Key); err != nil {
+		Logging.FromContext(c).Error("Error resetting login attempts", "key", keys[0].Key, "error", err)
+	}
+}
+
+// tooManyAttempts sets Retry-After in whole seconds and returns the matching
+// rate limited error
+func tooManyAttempts(c *gin.Context, wait time.Duration) error {
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	return Errors.RateLimited("Too many failed login attempts, try again later", seconds)
+}
@@ -0,0 +1,221 @@
+package Functions
+
+import (
+	"backend/Config"
+	"backend/Errors"
+	"backend/Schemas"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLoginThrottleDelay(t *testing.T) {
+	policy := loginThrottlePolicy{BaseDelay: time.Second, Lockout: 15 * time.Minute}
+	rule := throttleRule{BackoffAfter: 3, LockoutAfter: 10}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{9, 64 * time.Second},
+		{10, 15 * time.Minute},
+		{25, 15 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := policy.delay(rule, tt.failures); got != tt.want {
+			t.Errorf("delay(%d failures) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+
+	// The backoff never exceeds the lockout
+	capped := loginThrottlePolicy{BaseDelay: time.Minute, Lockout: 15 * time.Minute}
+	if got := capped.delay(rule, 9); got != 15*time.Minute {
+		t.Errorf("capped delay = %v, want 15m", got)
+	}
+}
+
+func TestLoginThrottleKeys(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddr     string
+		forwardedFor   string
+		wantIP         string
+	}{
+		{"direct client", nil, "203.0.113.7:5000", "", "203.0.113.7"},
+		{"forged header is ignored", nil, "203.0.113.7:5000", "10.9.9.9", "203.0.113.7"},
+		{"trusted proxy", []string{"192.0.2.1"}, "192.0.2.1:5000", "198.51.100.4", "198.51.100.4"},
+		{"untrusted proxy", []string{"192.0.2.1"}, "192.0.2.99:5000", "198.51.100.4", "192.0.2.99"},
+	}
+
+	policy := newLoginThrottlePolicy(Config.Default().Auth.Login)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, engine := gin.CreateTestContext(httptest.NewRecorder())
+			if err := engine.SetTrustedProxies(tt.trustedProxies); err != nil {
+				t.Fatal(err)
+			}
+			c.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+			c.Request.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				c.Request.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+
+			keys := policy.keys(c, "Alice")
+			if keys[0].Key != "user:alice" {
+				t.Errorf("username key = %q, want user:alice", keys[0].Key)
+			}
+			if keys[1].Key != "ip:"+tt.wantIP {
+				t.Errorf("IP key = %q, want ip:%s", keys[1].Key, tt.wantIP)
+			}
+		})
+	}
+}
+
+func TestLoginThrottle(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type attempt struct {
+		username   string
+		password   string
+		wantStatus int
+	}
+	wrong := func(username string, wantStatus int) attempt {
+		return attempt{username, "wrong", wantStatus}
+	}
+	right := func(username string, wantStatus int) attempt {
+		return attempt{username, "correct horse", wantStatus}
+	}
+
+	tests := []struct {
+		name     string
+		attempts []attempt
+	}{
+		{
+			name: "backs off after three failures",
+			attempts: []attempt{
+				wrong("alice", http.StatusUnauthorized),
+				wrong("alice", http.StatusUnauthorized),
+				wrong("alice", http.StatusTooManyRequests),
+				right("alice", http.StatusTooManyRequests),
+			},
+		},
+		{
+			name: "usernames ignore case",
+			attempts: []attempt{
+				wrong("alice", http.StatusUnauthorized),
+				wrong("ALICE", http.StatusUnauthorized),
+				wrong("Alice", http.StatusTooManyRequests),
+			},
+		},
+		{
+			name: "other usernames are not throttled",
+			attempts: []attempt{
+				wrong("alice", http.StatusUnauthorized),
+				wrong("alice", http.StatusUnauthorized),
+				wrong("alice", http.StatusTooManyRequests),
+				right("bob", http.StatusOK),
+			},
+		},
+		{
+			name: "success resets the username",
+			attempts: []attempt{
+				wrong("alice", http.StatusUnauthorized),
+				wrong("alice", http.StatusUnauthorized),
+				right("alice", http.StatusOK),
+				wrong("alice", http.StatusUnauthorized),
+				wrong("alice", http.StatusUnauthorized),
+			},
+		},
+		{
+			name: "unknown users are throttled too",
+			attempts: []attempt{
+				wrong("nobody", http.StatusUnauthorized),
+				wrong("nobody", http.StatusUnauthorized),
+				wrong("nobody", http.StatusTooManyRequests),
+			},
+		},
+		{
+			name: "the IP backs off across usernames",
+			attempts: []attempt{
+				wrong("a1", http.StatusUnauthorized),
+				wrong("a2", http.StatusUnauthorized),
+				wrong("a3", http.StatusUnauthorized),
+				wrong("a4", http.StatusUnauthorized),
+				wrong("a5", http.StatusTooManyRequests),
+				right("bob", http.StatusTooManyRequests),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t)
+			appConfig.Auth.Login = Config.LoginThrottle{
+				BackoffAfter:   3,
+				LockoutAfter:   10,
+				IPBackoffAfter: 5,
+				IPLockoutAfter: 50,
+				BaseDelay:      Config.Duration(time.Minute),
+				Lockout:        Config.Duration(15 * time.Minute),
+				Window:         Config.Duration(time.Hour),
+			}
+			for _, name := range []string{"alice", "bob"} {
+				createUser(t, name, func(user *Schemas.User) { user.Password = string(hash) })
+			}
+
+			router := newTestRouter()
+			router.POST("/login", Login)
+
+			for i, attempt := range tt.attempts {
+				body := `{"username":"` + attempt.username + `","password":"` + attempt.password + `"}`
+				recorder := serve(router, http.MethodPost, "/login", body, "")
+				if recorder.Code != attempt.wantStatus {
+					t.Fatalf("attempt %d as %s: status = %d, want %d: %s", i+1, attempt.username, recorder.Code, attempt.wantStatus, recorder.Body)
+				}
+				if attempt.wantStatus == http.StatusTooManyRequests {
+					if code := errorCode(t, recorder); code != Errors.CodeRateLimited {
+						t.Errorf("attempt %d: code = %q, want rate_limited", i+1, code)
+					}
+					if recorder.Header().Get("Retry-After") == "" {
+						t.Errorf("attempt %d: no Retry-After header", i+1)
+					}
+				}
+			}
+		})
+	}
+}
+
+// An unknown username must cost a bcrypt comparison like a wrong password,
+// or response times tell which usernames exist
+func TestLoginUnknownUserComparesPassword(t *testing.T) {
+	setupTest(t)
+
+	router := newTestRouter()
+	router.POST("/login", Login)
+
+	recorder := serve(router, http.MethodPost, "/login", `{"username":"nobody","password":"guess"}`, "")
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401: %s", recorder.Code, recorder.Body)
+	}
+	cost, err := bcrypt.Cost(dummyPasswordHash)
+	if err != nil {
+		t.Fatalf("no dummy password was compared: %v", err)
+	}
+	if cost != bcrypt.DefaultCost {
+		t.Errorf("dummy hash cost = %d, want the cost of stored passwords %d", cost, bcrypt.DefaultCost)
+	}
+}
@@ -0,0 +1,167 @@
+package Functions
+
+import (
+	"backend/Errors"
+	"backend/FunctionsHelper"
+	"backend/Logging"
+	"backend/Mailer"
+	"backend/Repository"
+	"backend/Schemas"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Time allowed to deliver an email before the request gives up on it
+const mailTimeout = 10 * time.Second
+
+// appLink builds a link to a page of the configured frontend
+func appLink(path string, token string) string {
+	base := strings.TrimRight(appConfig.HTTP.AppBaseURL, "/")
+	return base + path + "?token=" + url.QueryEscape(token)
+}
+
+// issueUserToken replaces the user's tokens for purpose with a new one and
+// returns it in plain text for mailing
+func issueUserToken(c *gin.Context, user Schemas.User, purpose string, ttl time.Duration) (string, error) {
+	token, hash, err := FunctionsHelper.NewOneTimeToken()
+	if err != nil {
+		return "", err
+	}
+
+	// Only the newest link works
+	if err := Repository.UserTokens().DeleteByUser(c, user.ID, purpose); err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	err = Repository.UserTokens().Create(c, Schemas.UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: hash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ForgotPassword mails a password reset link to the account with the given
+// email. The response is the same whether or not the account exists, so it
+// cannot be used to discover addresses.
+func ForgotPassword(c *gin.Context) {
+	var requestBody struct {
+		Email string `json:"email"`
+	}
+
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		Errors.Abort(c, Errors.InvalidBody(err))
+		return
+	}
+	if strings.TrimSpace(requestBody.Email) == "" {
+		Errors.Abort(c, Errors.Field("email", "email is required"))
+		return
+	}
+
+	response := gin.H{"message": "If an account with that email exists, a reset link has been sent"}
+
+	user, err := Repository.Users().FindByEmail(c, strings.TrimSpace(requestBody.Email))
+	if errors.Is(err, Repository.ErrNotFound) || (err == nil && user.Banned) {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	if err != nil {
+		Errors.Abort(c, Errors.Internal("Error requesting password reset", err))
+		return
+	}
+
+	ttl := time.Duration(appConfig.Auth.PasswordResetTTL)
+	token, err := issueUserToken(c, user, Schemas.TokenPurposePasswordReset, ttl)
+	if err != nil {
+		Errors.Abort(c, Errors.Internal("Error requesting password reset", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, mailTimeout)
+	defer cancel()
+
+	err = Mailer.Get().Send(ctx, Mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. "+
+			"If it was you, open this link within %d minutes:\n\n%s\n\n"+
+			"If it was not you, ignore this email, your password stays the same.\n",
+			user.Name, int(ttl.Minutes()), appLink("/reset-password", token)),
+	})
+	if err != nil {
+		// Answer as usual, the user can simply ask again
+		Logging.FromContext(c).Error("Error sending password reset email", "target_user", user.Name, "error", err)
+	}
+
+	recordSecurityEvent(c, user, Schemas.SecurityEventPasswordResetRequested)
+	c.JSON(http.StatusOK, response)
+}
+
+// ResetPassword sets a new password using a token from ForgotPassword. The
+// token works once and every existing session is signed out.
+func ResetPassword(c *gin.Context) {
+	var requestBody struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"newPassword"`
+	}
+
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		Errors.Abort(c, Errors.InvalidBody(err))
+		return
+	}
+	if requestBody.Token == "" {
+		Errors.Abort(c, Errors.Field("token", "token is required"))
+		return
+	}
+
+	// Check the password first so a weak one does not use up the token
+	if err := FunctionsHelper.ValidatePassword(requestBody.NewPassword); err != nil {
+		Errors.Abort(c, Errors.Field("newPassword", err.Error()))
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(requestBody.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		Errors.Abort(c, Errors.Internal("Error hashing password", err))
+		return
+	}
+
+	token, err := Repository.UserTokens().Consume(c, Schemas.TokenPurposePasswordReset, FunctionsHelper.HashOneTimeToken(requestBody.Token), time.Now().UTC())
+	if errors.Is(err, Repository.ErrNotFound) {
+		Errors.Abort(c, Errors.Field("token", "Invalid or expired token"))
+		return
+	}
+	if err != nil {
+		Errors.Abort(c, Errors.Internal("Error resetting password", err))
+		return
+	}
+
+	user, err := Repository.Users().FindByID(c, token.UserID)
+	if err != nil {
+		Errors.Abort(c, Errors.Field("token", "Invalid or expired token"))
+		return
+	}
+
+	// Also bumps the token version, signing out every session
+	if err := Repository.Users().UpdatePassword(c, user.ID, string(hashedPassword)); err != nil {
+		Errors.Abort(c, Errors.Internal("Error resetting password", err))
+		return
+	}
+
+	recordSecurityEvent(c, user, Schemas.SecurityEventPasswordReset)
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully, please log in"})
+}
@@ -0,0 +1,176 @@
+package Functions
+
+import (
+	"backend/Errors"
+	"backend/FunctionsHelper"
+	"backend/Repository"
+	"backend/Schemas"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPasswordReset(t *testing.T) {
+	type step struct {
+		token      string // "mailed", "first" for the first of two mails, or a literal
+		password   string
+		wantStatus int
+		wantField  string
+	}
+
+	tests := []struct {
+		name        string
+		forgotTwice bool
+		steps       []step
+	}{
+		{
+			name: "resets once",
+			steps: []step{
+				{token: "mailed", password: "better456", wantStatus: http.StatusOK},
+				{token: "mailed", password: "other789", wantStatus: http.StatusBadRequest, wantField: "token"},
+			},
+		},
+		{
+			name: "weak password keeps the token",
+			steps: []step{
+				{token: "mailed", password: "weak", wantStatus: http.StatusBadRequest, wantField: "newPassword"},
+				{token: "mailed", password: "better456", wantStatus: http.StatusOK},
+			},
+		},
+		{
+			name:        "only the newest link works",
+			forgotTwice: true,
+			steps: []step{
+				{token: "first", password: "better456", wantStatus: http.StatusBadRequest, wantField: "token"},
+				{token: "mailed", password: "better456", wantStatus: http.StatusOK},
+			},
+		},
+		{
+			name: "unknown token",
+			steps: []step{
+				{token: "made-up", password: "better456", wantStatus: http.StatusBadRequest, wantField: "token"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t)
+			mailer := useFileMailer(t)
+			user, accessToken := createUser(t, "alice", nil)
+
+			router := newTestRouter()
+			router.GET("/profile", AuthRequired, GetProfile)
+			router.POST("/password/forgot", ForgotPassword)
+			router.POST("/password/reset", ResetPassword)
+
+			requests := 1
+			if tt.forgotTwice {
+				requests = 2
+			}
+			for i := 0; i < requests; i++ {
+				if recorder := serve(router, http.MethodPost, "/password/forgot", `{"email":"ALICE@example.com"}`, ""); recorder.Code != http.StatusOK {
+					t.Fatalf("forgot: status = %d: %s", recorder.Code, recorder.Body)
+				}
+			}
+			sent := mailer.Sent()
+			if len(sent) != requests || sent[0].To != user.Email {
+				t.Fatalf("sent %+v, want %d mails to %s", sent, requests, user.Email)
+			}
+
+			reset := false
+			for i, step := range tt.steps {
+				token := step.token
+				switch token {
+				case "mailed":
+					token = mailedToken(t, sent[len(sent)-1])
+				case "first":
+					token = mailedToken(t, sent[0])
+				}
+
+				body := `{"token":"` + token + `","newPassword":"` + step.password + `"}`
+				recorder := serve(router, http.MethodPost, "/password/reset", body, "")
+				if recorder.Code != step.wantStatus {
+					t.Fatalf("step %d: status = %d, want %d: %s", i+1, recorder.Code, step.wantStatus, recorder.Body)
+				}
+				if step.wantField != "" {
+					if _, ok := errorBody(t, recorder).Fields[step.wantField]; !ok {
+						t.Errorf("step %d: %s, want a %s field error", i+1, recorder.Body, step.wantField)
+					}
+				}
+				reset = reset || step.wantStatus == http.StatusOK
+			}
+
+			// A reset signs out every session
+			wantProfile := http.StatusOK
+			if reset {
+				wantProfile = http.StatusUnauthorized
+			}
+			if recorder := serve(router, http.MethodGet, "/profile", "", accessToken); recorder.Code != wantProfile {
+				t.Errorf("old access token: status = %d, want %d", recorder.Code, wantProfile)
+			}
+		})
+	}
+}
+
+func TestPasswordResetExpires(t *testing.T) {
+	setupTest(t)
+	user, _ := createUser(t, "alice", nil)
+
+	token, hash, err := FunctionsHelper.NewOneTimeToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := time.Now().UTC().Add(-2 * time.Hour)
+	err = Repository.UserTokens().Create(context.Background(), Schemas.UserToken{
+		UserID:    user.ID,
+		Purpose:   Schemas.TokenPurposePasswordReset,
+		TokenHash: hash,
+		CreatedAt: created,
+		ExpiresAt: created.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := newTestRouter()
+	router.POST("/password/reset", ResetPassword)
+
+	recorder := serve(router, http.MethodPost, "/password/reset", `{"token":"`+token+`","newPassword":"better456"}`, "")
+	if recorder.Code != http.StatusBadRequest || errorCode(t, recorder) != Errors.CodeValidation {
+		t.Errorf("expired token: %d %s, want a 400 validation error", recorder.Code, recorder.Body)
+	}
+}
+
+func TestForgotPasswordHidesAccounts(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		banned   bool
+		wantMail bool
+	}{
+		{"known", "alice@example.com", false, true},
+		{"unknown", "nobody@example.com", false, false},
+		{"banned", "alice@example.com", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t)
+			mailer := useFileMailer(t)
+			createUser(t, "alice", func(user *Schemas.User) { user.Banned = tt.banned })
+
+			router := newTestRouter()
+			router.POST("/password/forgot", ForgotPassword)
+
+			recorder := serve(router, http.MethodPost, "/password/forgot", `{"email":"`+tt.email+`"}`, "")
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200 either way: %s", recorder.Code, recorder.Body)
+			}
+			if sent := len(mailer.Sent()); (sent == 1) != tt.wantMail {
+				t.Errorf("sent %d mails, want mail %v", sent, tt.wantMail)
+			}
+		})
+	}
+}
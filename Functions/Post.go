@@ -1,47 +1,40 @@
 package Functions
 
 import (
+	"backend/Errors"
 	"backend/FunctionsHelper"
-	"backend/Mongo"
+	"backend/Logging"
+	"backend/Repository"
 	"backend/Schemas"
-	cronjobs "backend/cronJobs"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func LockOldPostsHandler(c *gin.Context) {
-	// Call the LockOldPosts function
-	cronjobs.LockOldPosts()
-
-	// Respond with a success message
-	c.JSON(http.StatusOK, gin.H{"message": "LockOldPosts executed successfully"})
-}
-
 func AddTag(c *gin.Context) {
 	var tag Schemas.Tag
 
 	// Bind the JSON body to the tag struct
 	if err := c.ShouldBindJSON(&tag); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request"})
+		Errors.Abort(c, Errors.InvalidBody(err))
 		return
 	}
 
 	// Validate that the tag name is not empty
 	if tag.Name == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Tag name cannot be empty"})
+		Errors.Abort(c, Errors.Field("name", "Tag name cannot be empty"))
 		return
 	}
 
 	// Check the maximum length of the tag name (e.g., 50 characters)
 	if len(tag.Name) > 50 {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Tag name cannot exceed 50 characters"})
+		Errors.Abort(c, Errors.Field("name", "Tag name cannot exceed 50 characters"))
 		return
 	}
 
@@ -49,9 +42,9 @@ func AddTag(c *gin.Context) {
 	tag.DateAdded = time.Now().Format("2006-01-02")
 
 	// Insert the tag into the database
-	_, err := Mongo.GetCollection("tags").InsertOne(c, tag)
+	_, err := Repository.Tags().Create(c, tag)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Error adding tag"})
+		Errors.Abort(c, Errors.Internal("Error adding tag", err))
 		return
 	}
 
@@ -62,54 +55,70 @@ func AddTag(c *gin.Context) {
 func GetPost(c *gin.Context) {
 	postId := c.Query("post_id")
 	if postId == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "post_id is required"})
+		Errors.Abort(c, Errors.Field("post_id", "post_id is required"))
 		return
 	}
 
-	objId, _ := primitive.ObjectIDFromHex(postId)
+	objId, err := primitive.ObjectIDFromHex(postId)
+	if err != nil {
+		Errors.Abort(c, Errors.Field("post_id", "Invalid post_id"))
+		return
+	}
 
-	var post Schemas.Post
-	err := Mongo.GetCollection("studenci_district").FindOne(c, bson.M{"_id": objId}).Decode(&post)
+	post, err := Repository.Posts().FindByID(c, objId)
+	if errors.Is(err, Repository.ErrNotFound) {
+		Errors.Abort(c, Errors.NotFound("Post not found"))
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"message": "Post not found"})
+		Errors.Abort(c, Errors.Internal("Error loading post", err))
 		return
 	}
 
-	comments, err := GetAllCommentsForPost(post.ID.Hex())
+	comments, err := Repository.Comments().FindByPostID(c, post.ID.Hex())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Error decoding comments for post"})
+		Errors.Abort(c, Errors.Internal("Error decoding comments for post", err))
 		return
 	}
 
 	post.Comments = comments
 
-	c.JSON(http.StatusOK, post)
+	posts := []Schemas.Post{post}
+	if err := markLikedByMe(c, posts); err != nil {
+		Errors.Abort(c, Errors.Internal("Error loading likes", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, posts[0])
 }
 
 func SummarizePost(c *gin.Context) {
 	postId := c.Query("post_id")
 	if postId == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "post_id is required"})
+		Errors.Abort(c, Errors.Field("post_id", "post_id is required"))
 		return
 	}
 
 	objId, err := primitive.ObjectIDFromHex(postId)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid post_id"})
+		Errors.Abort(c, Errors.Field("post_id", "Invalid post_id"))
 		return
 	}
 
-	var post Schemas.Post
-	err = Mongo.GetCollection("studenci_district").FindOne(c, bson.M{"_id": objId}).Decode(&post)
+	post, err := Repository.Posts().FindByID(c, objId)
+	if errors.Is(err, Repository.ErrNotFound) {
+		Errors.Abort(c, Errors.NotFound("Post not found"))
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"message": "Post not found"})
+		Errors.Abort(c, Errors.Internal("Error loading post", err))
 		return
 	}
 
 	// Fetch all comments for the post
-	comments, err := GetAllCommentsForPost(post.ID.Hex())
+	comments, err := Repository.Comments().FindByPostID(c, post.ID.Hex())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Error decoding comments for post"})
+		Errors.Abort(c, Errors.Internal("Error decoding comments for post", err))
 		return
 	}
 
@@ -120,9 +129,9 @@ func SummarizePost(c *gin.Context) {
 	}
 
 	// Call AI service to summarize the content
-	aiSummary, err := FunctionsHelper.CallAIService(contentToSummarize, 200, "Summarize the following post and its comments concisely:")
+	aiSummary, err := FunctionsHelper.GetAIProvider().Complete(c, FunctionsHelper.SummaryPrompt, contentToSummarize, 200)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Error summarizing content"})
+		Errors.Abort(c, Errors.AIUnavailable(err))
 		return
 	}
 
@@ -133,110 +142,183 @@ func SummarizePost(c *gin.Context) {
 	})
 }
 
-// GetAllPosts allows optional filtering by tag names
-func GetAllPosts(c *gin.Context) {
-	// Retrieve the optional "tags" query parameter (comma-separated tag names)
-	tagsParam := c.Query("tags")
+// Values of the include_comments query parameter of post listings
+const (
+	includeCommentsNone  = "none"
+	includeCommentsCount = "count"
+	includeCommentsFull  = "full"
+)
 
-	// By default, we'll fetch all posts unless tags are provided
-	filter := bson.M{}
+// attachComments fills in the comments or comment counts of posts with a
+// single batched query, depending on mode.
+func attachComments(c *gin.Context, posts []Schemas.Post, mode string) error {
+	if mode == includeCommentsNone || len(posts) == 0 {
+		return nil
+	}
 
-	if tagsParam != "" {
-		// Split the comma-separated string into an array of tag names
-		tagNames := strings.Split(tagsParam, ",")
+	postIDs := make([]string, len(posts))
+	for i, post := range posts {
+		postIDs[i] = post.ID.Hex()
+	}
 
-		// Trim spaces around tag names (optional good practice)
-		for i := range tagNames {
-			tagNames[i] = strings.TrimSpace(tagNames[i])
+	if mode == includeCommentsCount {
+		counts, err := Repository.Comments().CountByPostIDs(c, postIDs)
+		if err != nil {
+			return err
 		}
-
-		// Look up the corresponding Tag documents and collect their IDs
-		var tagIDs []string
-		for _, tagName := range tagNames {
-			var dbTag Schemas.Tag
-			err := Mongo.GetCollection("tags").FindOne(c, bson.M{"name": tagName}).Decode(&dbTag)
-			if err == nil {
-				// If we find the tag, append its ID to the slice
-				tagIDs = append(tagIDs, dbTag.ID)
-			} else {
-				// If not found, skip it (log or ignore)
-				fmt.Printf("Tag not found for name: %s\n", tagName)
-			}
+		for i := range posts {
+			posts[i].CommentCount = counts[postIDs[i]]
 		}
+		return nil
+	}
+
+	commentsByPost, err := Repository.Comments().FindByPostIDs(c, postIDs)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		posts[i].Comments = commentsByPost[postIDs[i]]
+		posts[i].CommentCount = len(posts[i].Comments)
+	}
+	return nil
+}
+
+// parseLimit reads the optional "limit" query parameter of a listing
+func parseLimit(c *gin.Context) (int, error) {
+	limitParam := c.Query("limit")
+	if limitParam == "" {
+		return Repository.DefaultPageSize, nil
+	}
+
+	limit, err := strconv.Atoi(limitParam)
+	if err != nil || limit < 1 || limit > Repository.MaxPageSize {
+		return 0, Errors.Field("limit", fmt.Sprintf("limit must be between 1 and %d", Repository.MaxPageSize))
+	}
+	return limit, nil
+}
 
-		// If we found any valid tag IDs, filter posts whose "tags" array
-		// contains *at least one* of these tag IDs.
-		if len(tagIDs) > 0 {
-			filter = bson.M{"tags": bson.M{"$in": tagIDs}}
+// resolveTagFilter turns the optional comma-separated "tags" query parameter
+// into tag IDs. It returns nil when no tags were requested and a non-nil
+// empty slice, which matches no post at all, when none of them exist.
+func resolveTagFilter(c *gin.Context, tagsParam string) []string {
+	if tagsParam == "" {
+		return nil
+	}
+
+	// Split the comma-separated string into an array of tag names
+	tagNames := strings.Split(tagsParam, ",")
+
+	// Trim spaces around tag names (optional good practice)
+	for i := range tagNames {
+		tagNames[i] = strings.TrimSpace(tagNames[i])
+	}
+
+	// Look up the corresponding Tag documents and collect their IDs
+	tagIDs := []string{}
+	for _, tagName := range tagNames {
+		dbTag, err := Repository.Tags().FindByName(c, tagName)
+		if err == nil {
+			// If we find the tag, append its ID to the slice
+			tagIDs = append(tagIDs, dbTag.ID)
 		} else {
-			// If no tag IDs found at all, optionally return empty results
-			// or keep filter empty to return all posts.
-			// Here we choose to return empty results:
-			filter = bson.M{"_id": primitive.NilObjectID} // always false
+			// If not found, skip it
+			Logging.FromContext(c).Debug("Tag not found, skipping", "tag", tagName)
 		}
 	}
+	return tagIDs
+}
 
-	// Query the "studenci_district" collection using the filter
-	cursor, err := Mongo.GetCollection("studenci_district").Find(c, filter)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Error retrieving posts"})
+// GetAllPosts returns one page of posts, optionally filtered by tag names.
+// Query parameters: tags, sort (newest, most_liked, most_commented), limit,
+// cursor (the next_cursor of the previous page) and include_comments
+// (none, count or full, the default).
+func GetAllPosts(c *gin.Context) {
+	includeComments := c.DefaultQuery("include_comments", includeCommentsFull)
+	if includeComments != includeCommentsNone && includeComments != includeCommentsCount && includeComments != includeCommentsFull {
+		Errors.Abort(c, Errors.Field("include_comments", "include_comments must be one of none, count, full"))
 		return
 	}
-	defer cursor.Close(c)
-
-	var posts []Schemas.Post
 
-	// Iterate over the cursor and decode each post
-	for cursor.Next(c) {
-		var post Schemas.Post
-		if err := cursor.Decode(&post); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "Error decoding post"})
-			return
-		}
+	sortOrder, ok := Repository.ParsePostSort(c.Query("sort"))
+	if !ok {
+		Errors.Abort(c, Errors.Field("sort", "sort must be one of newest, most_liked, most_commented"))
+		return
+	}
 
-		// Optionally load comments for each post (if that's desired behavior)
-		comments, err := GetAllCommentsForPost(post.ID.Hex())
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "Error decoding comments for post"})
-			return
-		}
-		post.Comments = comments
+	limit, err := parseLimit(c)
+	if err != nil {
+		Errors.Abort(c, err)
+		return
+	}
 
-		posts = append(posts, post)
+	// By default, we'll fetch all posts unless tags are provided
+	tagIDs := resolveTagFilter(c, c.Query("tags"))
+
+	// Posts whose "tags" array contains *at least one* of the tag IDs
+	page, err := Repository.Posts().FindPage(c, Repository.PostQuery{
+		TagIDs: tagIDs,
+		Sort:   sortOrder,
+		Limit:  limit,
+		Cursor: c.Query("cursor"),
+	})
+	if errors.Is(err, Repository.ErrInvalidCursor) {
+		Errors.Abort(c, Errors.Field("cursor", "Invalid cursor"))
+		return
+	}
+	if err != nil {
+		Errors.Abort(c, Errors.Internal("Error retrieving posts", err))
+		return
 	}
 
-	if err := cursor.Err(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Cursor error"})
+	// Load comments for the whole page at once
+	posts := page.Posts
+	if err := attachComments(c, posts, includeComments); err != nil {
+		Errors.Abort(c, Errors.Internal("Error decoding comments for post", err))
+		return
+	}
+	if err := markLikedByMe(c, posts); err != nil {
+		Errors.Abort(c, Errors.Internal("Error loading likes", err))
 		return
 	}
 
-	// Return the filtered posts
-	c.JSON(http.StatusOK, posts)
+	// Return the page together with the cursor of the next one
+	c.JSON(http.StatusOK, gin.H{
+		"posts":       posts,
+		"next_cursor": page.NextCursor,
+	})
 }
 
 func CreatePost(c *gin.Context) {
-	var post Schemas.Post
+	// Only these fields come from the client, counters, lock state and the
+	// ID are the server's
+	var requestBody struct {
+		Problem string   `json:"problem"`
+		Tags    []string `json:"tags"`
+	}
 
-	// Bind the JSON body to the post struct
-	if err := c.ShouldBindJSON(&post); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request"})
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		Errors.Abort(c, Errors.InvalidBody(err))
 		return
 	}
+	post := Schemas.Post{Problem: requestBody.Problem, Tags: requestBody.Tags}
 
-	// Validate that username and problem are not empty
-	if post.Username == "" || post.Problem == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Username and problem cannot be empty"})
+	// The author is always the authenticated user, never the request body
+	user, ok := CurrentUser(c)
+	if !ok {
+		Errors.Abort(c, Errors.Unauthorized("Authentication required"))
 		return
 	}
+	post.Username = user.Name
 
-	if len(post.Username) > 50 {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Username cannot exceed 50 characters"})
+	// Validate that problem is not empty
+	if post.Problem == "" {
+		Errors.Abort(c, Errors.Field("problem", "Problem cannot be empty"))
 		return
 	}
 
 	// Check the maximum length of the problem description (e.g., 500 characters)
 	if len(post.Problem) > 500 {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Problem description cannot exceed 500 characters"})
+		Errors.Abort(c, Errors.Field("problem", "Problem description cannot exceed 500 characters"))
 		return
 	}
 
@@ -245,15 +327,14 @@ func CreatePost(c *gin.Context) {
 	if len(post.Tags) > 0 {
 		finalTagIDs := []string{}
 		for _, tagName := range post.Tags {
-			var dbTag Schemas.Tag
 			// Try to find the tag by name in the "tags" collection
-			err := Mongo.GetCollection("tags").FindOne(c, bson.M{"name": tagName}).Decode(&dbTag)
+			dbTag, err := Repository.Tags().FindByName(c, tagName)
 			if err == nil {
 				// If found, append the Tag's ID to finalTagIDs
 				finalTagIDs = append(finalTagIDs, dbTag.ID)
 			} else {
 				// If not found, we skip it (do not create a new tag)
-				log.Printf("Tag not found for name: %s, skipping", tagName)
+				Logging.FromContext(c).Debug("Tag not found, skipping", "tag", tagName)
 			}
 		}
 		// Replace the post's Tags with the found tag IDs
@@ -262,39 +343,36 @@ func CreatePost(c *gin.Context) {
 	// ─────────────────────────────────────────────────────────────────────────────
 
 	// Set the current date automatically on the backend
-	post.Date = time.Now().Format("2006-01-02")
+	now := time.Now().UTC()
+	post.Date = now.Format("2006-01-02")
+	post.CreatedAt = now
 
 	// AI check for appropriate post
-	aiResponseV, err := FunctionsHelper.CallAIService(post.Problem, 1, "You are a bot that checks if the post is appropriate or not. By appropriate it is meant there are bad words. If it is appropriate return 1; else return 0.")
+	appropriate, err := FunctionsHelper.IsContentAppropriate(c, post.Problem)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Error generating AI response"})
+		Errors.Abort(c, Errors.AIUnavailable(err))
 		return
 	}
 
-	if aiResponseV == "0" {
-		log.Println("AI Response not approved: AI returned 0")
-		c.JSON(http.StatusForbidden, gin.H{"message": "Not approved by AI"}) // HTTP 403 Forbidden
+	if !appropriate {
+		Logging.FromContext(c).Info("Post rejected by AI moderation", "ai_provider", FunctionsHelper.GetAIProvider().Name())
+		Errors.Abort(c, Errors.Forbidden("Not approved by AI"))
 		return
 	}
 
-	// Insert the post into the database
-	insertResult, err := Mongo.GetCollection("studenci_district").InsertOne(c, post)
+	// Generate an AI response for the post problem before storing anything,
+	// so a failing provider leaves no post behind for a retry to duplicate
+	aiResponse, err := FunctionsHelper.GetAIProvider().Complete(c, FunctionsHelper.AnswerPrompt, post.Problem, 50)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Error creating post"})
+		Errors.Abort(c, Errors.AIUnavailable(err))
 		return
 	}
+	Logging.FromContext(c).Debug("AI answered post", "ai_provider", FunctionsHelper.GetAIProvider().Name(), "answer_length", len(aiResponse))
 
-	// Generate an AI response for the post problem
-	aiResponse, err := FunctionsHelper.CallAIService(post.Problem, 50, "You are an AI assistant for a Q&A site. Your purpose is to provide the first helpful and concise answer to users' questions. There is no followup. There is just your answer and it is not posible to ask for more information.")
+	// Insert the post into the database
+	postID, err := Repository.Posts().Create(c, post)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Error generating AI response"})
-		return
-	}
-	log.Printf("AI Response: %s", aiResponse)
-
-	postID, ok := insertResult.InsertedID.(primitive.ObjectID)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Error retrieving inserted post ID"})
+		Errors.Abort(c, Errors.Internal("Error creating post", err))
 		return
 	}
 
@@ -302,17 +380,23 @@ func CreatePost(c *gin.Context) {
 	comment := Schemas.Comment{
 		Username:    "AI",
 		Date:        time.Now().Format("2006-01-02"),
+		CreatedAt:   time.Now().UTC(),
 		Description: aiResponse,
 		PostId:      postID.Hex(), // Use the post's ID as reference
 	}
 
-	// Insert the AI-generated comment into the comments collection
-	_, err = Mongo.GetCollection("melje_district").InsertOne(c, comment)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Error adding AI comment"})
+	// The post is stored, failing now would invite a duplicate, so a lost
+	// AI comment is only logged
+	if _, err := Repository.Comments().Create(c, comment); err != nil {
+		Logging.FromContext(c).Error("Error adding AI comment", "post_id", postID.Hex(), "error", err)
+		c.JSON(http.StatusOK, gin.H{"message": "Post added successfully"})
 		return
 	}
 
+	if err := Repository.Posts().IncrementCommentCount(c, postID, 1); err != nil {
+		Logging.FromContext(c).Error("Error updating comment count", "post_id", postID.Hex(), "error", err)
+	}
+
 	// Respond with success message
 	c.JSON(http.StatusOK, gin.H{"message": "Post and AI comment added successfully"})
 }
@@ -320,86 +404,179 @@ func CreatePost(c *gin.Context) {
 func DeletePost(c *gin.Context) {
 	postId := c.Query("post_id")
 	if postId == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "post_id is required"})
+		Errors.Abort(c, Errors.Field("post_id", "post_id is required"))
 		return
 	}
 
-	objId, _ := primitive.ObjectIDFromHex(postId)
+	objId, err := primitive.ObjectIDFromHex(postId)
+	if err != nil {
+		Errors.Abort(c, Errors.Field("post_id", "Invalid post_id"))
+		return
+	}
 
-	_, err := Mongo.GetCollection("studenci_district").DeleteOne(c, bson.M{"_id": objId})
+	post, err := Repository.Posts().FindByID(c, objId)
+	if errors.Is(err, Repository.ErrNotFound) {
+		Errors.Abort(c, Errors.NotFound("Post not found"))
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"message": "Post not found"})
+		Errors.Abort(c, Errors.Internal("Error deleting post", err))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Post deleted successfully"})
+	// Only the author or a moderator may delete a post
+	if err := authorizeAuthor(c, post.Username); err != nil {
+		Errors.Abort(c, err)
+		return
+	}
+
+	deleted, err := Repository.Posts().Delete(c, objId)
+	if err != nil {
+		Errors.Abort(c, Errors.Internal("Error deleting post", err))
+		return
+	}
+	if !deleted {
+		Errors.Abort(c, Errors.NotFound("Post not found"))
+		return
+	}
+
+	// Remove the post's comments and all likes of the post and its comments
+	// so none are left orphaned
+	logger := Logging.FromContext(c)
+	comments, err := Repository.Comments().FindByPostID(c, post.ID.Hex())
+	if err != nil {
+		logger.Error("Error loading comments of post", "post_id", post.ID.Hex(), "error", err)
+	}
+	removed, err := Repository.Comments().DeleteByPostID(c, post.ID.Hex())
+	if err != nil {
+		logger.Error("Error deleting comments of post", "post_id", post.ID.Hex(), "error", err)
+	}
+
+	if _, err := Repository.Likes().DeleteByTarget(c, Schemas.LikeTargetPost, []primitive.ObjectID{post.ID}); err != nil {
+		logger.Error("Error deleting likes of post", "post_id", post.ID.Hex(), "error", err)
+	}
+	commentIDs := make([]primitive.ObjectID, 0, len(comments))
+	for _, comment := range comments {
+		commentIDs = append(commentIDs, comment.ID)
+	}
+	if _, err := Repository.Likes().DeleteByTarget(c, Schemas.LikeTargetComment, commentIDs); err != nil {
+		logger.Error("Error deleting likes of comments", "post_id", post.ID.Hex(), "error", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Post deleted successfully", "deleted_comments": removed})
+}
+
+// lockedError fails with locked when post is locked, saying since when and
+// why
+func lockedError(post Schemas.Post) error {
+	if !post.Locked {
+		return nil
+	}
+
+	err := Errors.Locked("Post is locked").WithDetail("post_id", post.ID.Hex())
+	if post.LockReason != "" {
+		err.WithDetail("lock_reason", post.LockReason)
+	}
+	if post.LockedAt != nil {
+		err.WithDetail("locked_at", post.LockedAt)
+	}
+	return err
 }
-func LikePost(c *gin.Context) {
+
+// LockPost locks a post with an optional reason
+func LockPost(c *gin.Context) {
 	var requestBody struct {
-		PostID string `json:"post_id"`
+		Reason string `json:"reason"`
 	}
 
-	// Bind JSON body to the requestBody struct
-	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request body"})
-		return
+	// The body is optional, a lock without reason is fine
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&requestBody); err != nil {
+			Errors.Abort(c, Errors.InvalidBody(err))
+			return
+		}
 	}
 
-	// Validate post_id
-	if requestBody.PostID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "post_id is required"})
+	objId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		Errors.Abort(c, Errors.Field("post_id", "Invalid post_id"))
 		return
 	}
 
-	// Convert post_id to ObjectID
-	objId, err := primitive.ObjectIDFromHex(requestBody.PostID)
+	user, _ := CurrentUser(c)
+	matched, err := Repository.Posts().Lock(c, objId, strings.TrimSpace(requestBody.Reason), user.Name, time.Now().UTC())
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid post_id"})
+		Errors.Abort(c, Errors.Internal("Failed to lock post", err))
+		return
+	}
+	if !matched {
+		Errors.Abort(c, Errors.NotFound("Post not found"))
 		return
 	}
 
-	// Access the collection
-	collection := Mongo.GetCollection("studenci_district")
-
-	// Increment the LikeCount by 1
-	update := bson.M{"$inc": bson.M{"likeCount": 1}}
-	filter := bson.M{"_id": objId}
+	c.JSON(http.StatusOK, gin.H{"message": "Post locked successfully"})
+}
 
-	result, err := collection.UpdateOne(c, filter, update)
+// UnlockPost reopens a locked post
+func UnlockPost(c *gin.Context) {
+	objId, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to update post", "error": err.Error()})
+		Errors.Abort(c, Errors.Field("post_id", "Invalid post_id"))
 		return
 	}
 
-	// Check if a document was updated
-	if result.MatchedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"message": "Post not found"})
+	matched, err := Repository.Posts().Unlock(c, objId)
+	if err != nil {
+		Errors.Abort(c, Errors.Internal("Failed to unlock post", err))
+		return
+	}
+	if !matched {
+		Errors.Abort(c, Errors.NotFound("Post not found"))
 		return
 	}
 
-	// Debugging: Log the update result
-	fmt.Printf("Update result: %+v\n", result)
+	c.JSON(http.StatusOK, gin.H{"message": "Post unlocked successfully"})
+}
 
-	// Return success response
-	c.JSON(http.StatusOK, gin.H{"message": "Post liked successfully"})
+// PinPost pins a post, pinned posts are exempt from automatic locking
+func PinPost(c *gin.Context) {
+	setPinned(c, true)
 }
 
-func GetAllTagNames(c *gin.Context) {
-	// Get the "tags" collection
-	tagsCollection := Mongo.GetCollection("tags")
+// UnpinPost unpins a post
+func UnpinPost(c *gin.Context) {
+	setPinned(c, false)
+}
 
-	// Find all documents in the "tags" collection
-	cursor, err := tagsCollection.Find(c, bson.M{})
+func setPinned(c *gin.Context, pinned bool) {
+	objId, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Error retrieving tags"})
+		Errors.Abort(c, Errors.Field("post_id", "Invalid post_id"))
 		return
 	}
-	defer cursor.Close(c)
 
-	// Decode the results into a slice of Tag structs
-	var tagList []Schemas.Tag
-	if err := cursor.All(c, &tagList); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Error decoding tags"})
+	matched, err := Repository.Posts().SetPinned(c, objId, pinned)
+	if err != nil {
+		Errors.Abort(c, Errors.Internal("Failed to update post", err))
+		return
+	}
+	if !matched {
+		Errors.Abort(c, Errors.NotFound("Post not found"))
+		return
+	}
+
+	if pinned {
+		c.JSON(http.StatusOK, gin.H{"message": "Post pinned successfully"})
+	} else {
+		c.JSON(http.StatusOK, gin.H{"message": "Post unpinned successfully"})
+	}
+}
+
+func GetAllTagNames(c *gin.Context) {
+	// Find all tags
+	tagList, err := Repository.Tags().FindAll(c)
+	if err != nil {
+		Errors.Abort(c, Errors.Internal("Error retrieving tags", err))
 		return
 	}
 
@@ -417,25 +594,24 @@ func GetTagNameByID(c *gin.Context) {
 	// Retrieve "id" from the query string
 	idParam := c.Query("id")
 	if idParam == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "id query parameter is required"})
+		Errors.Abort(c, Errors.Field("id", "id query parameter is required"))
 		return
 	}
 
-	// Convert the hex string to a MongoDB ObjectID
-	oid, err := primitive.ObjectIDFromHex(idParam)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid MongoDB ID format"})
+	// Validate the hex string is a MongoDB ObjectID
+	if !primitive.IsValidObjectID(idParam) {
+		Errors.Abort(c, Errors.Field("id", "Invalid MongoDB ID format"))
 		return
 	}
 
-	// Query the "tags" collection
-	tagsCollection := Mongo.GetCollection("tags")
-
 	// Attempt to find the tag document by _id
-	var dbTag Schemas.Tag
-	err = tagsCollection.FindOne(c, bson.M{"_id": oid}).Decode(&dbTag)
+	dbTag, err := Repository.Tags().FindByID(c, idParam)
+	if errors.Is(err, Repository.ErrNotFound) {
+		Errors.Abort(c, Errors.NotFound("Tag not found"))
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"message": "Tag not found"})
+		Errors.Abort(c, Errors.Internal("Error loading tag", err))
 		return
 	}
 
@@ -0,0 +1,247 @@
+package Functions
+
+import (
+	"backend/Errors"
+	"backend/FunctionsHelper"
+	"backend/Repository"
+	"backend/Schemas"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPinPost(t *testing.T) {
+	setupTest(t)
+	ctx := context.Background()
+
+	_, userToken := createUser(t, "alice", nil)
+	_, moderatorToken := createUser(t, "mod", func(user *Schemas.User) { user.Role = Schemas.RoleModerator })
+	postID, err := Repository.Posts().Create(ctx, Schemas.Post{Problem: "Pin me", Username: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := newTestRouter()
+	router.POST("/admin/posts/:id/pin", AuthRequired, RequirePermission(PermissionLockPost), PinPost)
+	router.DELETE("/admin/posts/:id/pin", AuthRequired, RequirePermission(PermissionLockPost), UnpinPost)
+
+	tests := []struct {
+		name       string
+		method     string
+		id         string
+		token      string
+		wantStatus int
+		wantCode   Errors.Code
+		wantPinned bool
+	}{
+		{"user may not pin", http.MethodPost, postID.Hex(), userToken, http.StatusForbidden, Errors.CodeForbidden, false},
+		{"invalid id", http.MethodPost, "nope", moderatorToken, http.StatusBadRequest, Errors.CodeValidation, false},
+		{"unknown post", http.MethodPost, primitive.NewObjectID().Hex(), moderatorToken, http.StatusNotFound, Errors.CodeNotFound, false},
+		{"pin", http.MethodPost, postID.Hex(), moderatorToken, http.StatusOK, "", true},
+		{"pin again", http.MethodPost, postID.Hex(), moderatorToken, http.StatusOK, "", true},
+		{"unpin", http.MethodDelete, postID.Hex(), moderatorToken, http.StatusOK, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := serve(router, tt.method, "/admin/posts/"+tt.id+"/pin", "", tt.token)
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+			if tt.wantCode != "" {
+				if code := errorCode(t, recorder); code != tt.wantCode {
+					t.Errorf("code = %q, want %q", code, tt.wantCode)
+				}
+			}
+
+			post, err := Repository.Posts().FindByID(ctx, postID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if post.Pinned != tt.wantPinned {
+				t.Errorf("pinned = %v, want %v", post.Pinned, tt.wantPinned)
+			}
+		})
+	}
+}
+
+// answerFailingProvider moderates like the fake provider but fails every
+// answer
+type answerFailingProvider struct {
+	*FunctionsHelper.FakeAIProvider
+}
+
+func (p answerFailingProvider) Complete(ctx context.Context, systemPrompt string, question string, maxTokens int) (string, error) {
+	if systemPrompt == FunctionsHelper.AnswerPrompt {
+		return "", errors.New("provider timed out")
+	}
+	return p.FakeAIProvider.Complete(ctx, systemPrompt, question, maxTokens)
+}
+
+func TestCreatePost(t *testing.T) {
+	tests := []struct {
+		name         string
+		problem      string
+		provider     func(fake *FunctionsHelper.FakeAIProvider) FunctionsHelper.AIProvider
+		wantStatus   int
+		wantCode     Errors.Code
+		wantPosts    int
+		wantComments int
+	}{
+		{
+			name:         "stores the post and the AI answer",
+			problem:      "How do I center a div?",
+			wantStatus:   http.StatusOK,
+			wantPosts:    1,
+			wantComments: 1,
+		},
+		{
+			name:       "rejected by moderation",
+			problem:    "Some badword",
+			wantStatus: http.StatusForbidden,
+			wantCode:   Errors.CodeForbidden,
+		},
+		{
+			name:    "moderation unavailable",
+			problem: "How do I center a div?",
+			provider: func(fake *FunctionsHelper.FakeAIProvider) FunctionsHelper.AIProvider {
+				fake.Err = errors.New("provider down")
+				return fake
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantCode:   Errors.CodeAIUnavailable,
+		},
+		{
+			name:    "answer unavailable stores nothing",
+			problem: "How do I center a div?",
+			provider: func(fake *FunctionsHelper.FakeAIProvider) FunctionsHelper.AIProvider {
+				return answerFailingProvider{fake}
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantCode:   Errors.CodeAIUnavailable,
+		},
+		{
+			name:       "empty problem",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   Errors.CodeValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := setupTest(t)
+			if tt.provider != nil {
+				FunctionsHelper.SetAIProvider(tt.provider(fake))
+			}
+			_, token := createUser(t, "alice", nil)
+
+			router := newTestRouter()
+			router.POST("/post", AuthRequired, CreatePost)
+
+			body, _ := json.Marshal(map[string]string{"problem": tt.problem})
+			recorder := serve(router, http.MethodPost, "/post", string(body), token)
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+			if tt.wantCode != "" {
+				if code := errorCode(t, recorder); code != tt.wantCode {
+					t.Errorf("code = %q, want %q", code, tt.wantCode)
+				}
+			}
+
+			page, err := Repository.Posts().FindPage(context.Background(), Repository.PostQuery{Limit: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(page.Posts) != tt.wantPosts {
+				t.Fatalf("stored %d posts, want %d", len(page.Posts), tt.wantPosts)
+			}
+			if tt.wantPosts == 0 {
+				return
+			}
+
+			post := page.Posts[0]
+			comments, err := Repository.Comments().FindByPostID(context.Background(), post.ID.Hex())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(comments) != tt.wantComments || post.CommentCount != tt.wantComments {
+				t.Errorf("%d comments, comment count %d, want %d", len(comments), post.CommentCount, tt.wantComments)
+			}
+			if len(comments) > 0 && (comments[0].Username != "AI" || comments[0].Description != "Fake answer to: "+tt.problem) {
+				t.Errorf("comment = %+v, want the fake AI answer", comments[0])
+			}
+		})
+	}
+}
+
+func TestDeletePost(t *testing.T) {
+	tests := []struct {
+		name       string
+		deleter    string // Empty deletes anonymously
+		wantStatus int
+		wantCode   Errors.Code
+	}{
+		{"author", "alice", http.StatusOK, ""},
+		{"other user", "bob", http.StatusForbidden, Errors.CodeForbidden},
+		{"moderator", "mod", http.StatusOK, ""},
+		{"admin", "admin", http.StatusOK, ""},
+		{"anonymous", "", http.StatusUnauthorized, Errors.CodeUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t)
+			ctx := context.Background()
+
+			tokens := map[string]string{}
+			for name, role := range map[string]string{"alice": Schemas.RoleUser, "bob": Schemas.RoleUser, "mod": Schemas.RoleModerator, "admin": Schemas.RoleAdmin} {
+				_, tokens[name] = createUser(t, name, func(user *Schemas.User) { user.Role = role })
+			}
+			postID, err := Repository.Posts().Create(ctx, Schemas.Post{Problem: "Mine", Username: "alice"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			commentID, err := Repository.Comments().Create(ctx, Schemas.Comment{Description: "Reply", Username: "bob", PostId: postID.Hex()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Repository.Likes().Like(ctx, primitive.NewObjectID(), Schemas.LikeTargetComment, commentID); err != nil {
+				t.Fatal(err)
+			}
+
+			router := newTestRouter()
+			router.DELETE("/post", AuthRequired, DeletePost)
+
+			recorder := serve(router, http.MethodDelete, "/post?post_id="+postID.Hex(), "", tokens[tt.deleter])
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+			if tt.wantCode != "" {
+				if code := errorCode(t, recorder); code != tt.wantCode {
+					t.Errorf("code = %q, want %q", code, tt.wantCode)
+				}
+			}
+
+			// A deleted post takes its comments and their likes along
+			deleted := tt.wantStatus == http.StatusOK
+			if _, err := Repository.Posts().FindByID(ctx, postID); errors.Is(err, Repository.ErrNotFound) != deleted {
+				t.Errorf("finding the post: %v, want deleted %v", err, deleted)
+			}
+			if _, err := Repository.Comments().FindByID(ctx, commentID); errors.Is(err, Repository.ErrNotFound) != deleted {
+				t.Errorf("finding the comment: %v, want deleted %v", err, deleted)
+			}
+			removedLikes, err := Repository.Likes().DeleteByTarget(ctx, Schemas.LikeTargetComment, []primitive.ObjectID{commentID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (removedLikes == 0) != deleted {
+				t.Errorf("%d likes of the comment left, want deleted %v", removedLikes, deleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package Functions
+
+import (
+	"backend/Config"
+	"backend/Errors"
+	"backend/Logging"
+	"backend/Repository"
+	"context"
+	"log/slog"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitGroup names a set of routes that share one rate limit
+type RateLimitGroup string
+
+const (
+	RateLimitAuth   RateLimitGroup = "auth"   // Login, registration and account recovery
+	RateLimitWrites RateLimitGroup = "writes" // Authenticated changes
+	RateLimitAI     RateLimitGroup = "ai"     // Endpoints that call the AI provider
+	RateLimitReads  RateLimitGroup = "reads"  // Public reads
+	RateLimitChat   RateLimitGroup = "chat"   // Messages sent over a chat WebSocket
+)
+
+var (
+	localRateLimits     Repository.RateLimitRepository
+	localRateLimitsOnce sync.Once
+)
+
+// rateLimitStore returns where buckets are kept. They follow the storage
+// backend, so instances sharing a database share their limits, unless the
+// rate limit backend is memory, which limits every instance on its own.
+func rateLimitStore() Repository.RateLimitRepository {
+	if appConfig.RateLimit.Backend != "memory" {
+		return Repository.RateLimits()
+	}
+
+	localRateLimitsOnce.Do(func() {
+		localRateLimits = Repository.NewMemoryRateLimitRepository()
+	})
+	return localRateLimits
+}
+
+// rateLimiter is the token bucket configuration of one group
+type rateLimiter struct {
+	group  RateLimitGroup
+	bucket Repository.TokenBucket
+}
+
+// newRateLimiter reads the configured limit of group, e.g. "10/1m" for bursts
+// of 10 requests and 10 more every minute. It reports false when the group is
+// not limited ("off").
+func newRateLimiter(group RateLimitGroup) (rateLimiter, bool) {
+	spec, ok := appConfig.RateLimit.Limits[string(group)]
+	if !ok {
+		spec = Config.Default().RateLimit.Limits[string(group)]
+	}
+	if spec == "off" {
+		return rateLimiter{}, false
+	}
+
+	requests, per, err := Config.ParseRateLimit(spec)
+	if err != nil {
+		slog.Error("Invalid rate limit, not limiting", "group", group, "limit", spec, "error", err)
+		return rateLimiter{}, false
+	}
+
+	return rateLimiter{group: group, bucket: Repository.TokenBucket{
+		Capacity:        float64(requests),
+		RefillPerSecond: float64(requests) / per.Seconds(),
+	}}, true
+}
+
+// rateLimitState is the outcome of one request against a bucket
+type rateLimitState struct {
+	Allowed    bool
+	Remaining  int
+	Reset      int // Seconds until the bucket is full again
+	RetryAfter int // Seconds until the next token, 0 when allowed
+}
+
+// take spends a token of subject's bucket. The limiter fails open: when the
+// store is unavailable the request is let through and ok is false.
+func (l rateLimiter) take(ctx context.Context, subject string) (state rateLimitState, ok bool) {
+	bucket, err := rateLimitStore().Take(ctx, string(l.group)+":"+subject, l.bucket, time.Now())
+	if err != nil {
+		Logging.FromContext(ctx).Error("Error taking rate limit token", "group", l.group, "subject", subject, "error", err)
+		return rateLimitState{Allowed: true}, false
+	}
+
+	state = rateLimitState{
+		Allowed:   bucket.Allowed,
+		Remaining: int(math.Floor(bucket.Tokens)),
+		Reset:     int(math.Ceil((l.bucket.Capacity - bucket.Tokens) / l.bucket.RefillPerSecond)),
+	}
+	if !bucket.Allowed {
+		state.RetryAfter = int(math.Ceil((1 - bucket.Tokens) / l.bucket.RefillPerSecond))
+	}
+	return state, true
+}
+
+// rateLimitSubject is who a request is charged to: the authenticated user
+// when there is one, otherwise the client IP
+func rateLimitSubject(c *gin.Context) string {
+	if user, ok := CurrentUser(c); ok {
+		return "user:" + user.ID.Hex()
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// RateLimit returns a token bucket middleware for group. It sets the
+// X-RateLimit-* headers and answers 429 with Retry-After when the bucket is
+// empty. Placed after AuthRequired or OptionalAuth it limits the
+// authenticated user, otherwise the IP.
+func RateLimit(group RateLimitGroup) gin.HandlerFunc {
+	limiter, enabled := newRateLimiter(group)
+	if !enabled {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	return func(c *gin.Context) {
+		state, ok := limiter.take(c, rateLimitSubject(c))
+		if !ok {
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(int(limiter.bucket.Capacity)))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(state.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(state.Reset))
+		if !state.Allowed {
+			c.Header("Retry-After", strconv.Itoa(state.RetryAfter))
+			Errors.Abort(c, Errors.RateLimited("Too many requests, slow down", state.RetryAfter))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,132 @@
+package Functions
+
+import (
+	"backend/Errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimit(t *testing.T) {
+	type request struct {
+		path          string
+		user          string // Signs the request in as this user when set
+		remoteAddr    string
+		forwardedFor  string
+		wantStatus    int
+		wantRemaining string
+	}
+	const (
+		clientA = "203.0.113.7:5000"
+		clientB = "203.0.113.8:5000"
+	)
+
+	tests := []struct {
+		name     string
+		limit    string
+		requests []request
+	}{
+		{
+			name:  "per IP",
+			limit: "2/1m",
+			requests: []request{
+				{path: "/public", remoteAddr: clientA, wantStatus: http.StatusOK, wantRemaining: "1"},
+				{path: "/public", remoteAddr: clientA, wantStatus: http.StatusOK, wantRemaining: "0"},
+				{path: "/public", remoteAddr: clientA, wantStatus: http.StatusTooManyRequests, wantRemaining: "0"},
+				{path: "/public", remoteAddr: clientB, wantStatus: http.StatusOK, wantRemaining: "1"},
+			},
+		},
+		{
+			name:  "forged X-Forwarded-For does not bypass the limit",
+			limit: "2/1m",
+			requests: []request{
+				{path: "/public", remoteAddr: clientA, forwardedFor: "10.0.0.1", wantStatus: http.StatusOK, wantRemaining: "1"},
+				{path: "/public", remoteAddr: clientA, forwardedFor: "10.0.0.2", wantStatus: http.StatusOK, wantRemaining: "0"},
+				{path: "/public", remoteAddr: clientA, forwardedFor: "10.0.0.3", wantStatus: http.StatusTooManyRequests, wantRemaining: "0"},
+			},
+		},
+		{
+			name:  "per user",
+			limit: "2/1m",
+			requests: []request{
+				{path: "/private", user: "alice", remoteAddr: clientA, wantStatus: http.StatusOK, wantRemaining: "1"},
+				{path: "/private", user: "alice", remoteAddr: clientB, wantStatus: http.StatusOK, wantRemaining: "0"},
+				{path: "/private", user: "alice", remoteAddr: clientB, wantStatus: http.StatusTooManyRequests, wantRemaining: "0"},
+				{path: "/private", user: "bob", remoteAddr: clientA, wantStatus: http.StatusOK, wantRemaining: "1"},
+				{path: "/public", remoteAddr: clientA, wantStatus: http.StatusOK, wantRemaining: "1"},
+			},
+		},
+		{
+			name:  "off",
+			limit: "off",
+			requests: []request{
+				{path: "/public", remoteAddr: clientA, wantStatus: http.StatusOK},
+				{path: "/public", remoteAddr: clientA, wantStatus: http.StatusOK},
+				{path: "/public", remoteAddr: clientA, wantStatus: http.StatusOK},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t)
+			appConfig.RateLimit.Limits[string(RateLimitWrites)] = tt.limit
+			tokens := map[string]string{}
+			for _, name := range []string{"alice", "bob"} {
+				_, tokens[name] = createUser(t, name, nil)
+			}
+
+			router := newTestRouter()
+			if err := router.SetTrustedProxies(nil); err != nil {
+				t.Fatal(err)
+			}
+			limit := RateLimit(RateLimitWrites)
+			respond := func(c *gin.Context) { c.Status(http.StatusOK) }
+			router.GET("/public", limit, respond)
+			router.GET("/private", AuthRequired, limit, respond)
+
+			for i, r := range tt.requests {
+				req := httptest.NewRequest(http.MethodGet, r.path, nil)
+				req.RemoteAddr = r.remoteAddr
+				if r.forwardedFor != "" {
+					req.Header.Set("X-Forwarded-For", r.forwardedFor)
+				}
+				if r.user != "" {
+					req.Header.Set("Authorization", "Bearer "+tokens[r.user])
+				}
+				recorder := httptest.NewRecorder()
+				router.ServeHTTP(recorder, req)
+
+				if recorder.Code != r.wantStatus {
+					t.Fatalf("request %d: status = %d, want %d: %s", i+1, recorder.Code, r.wantStatus, recorder.Body)
+				}
+				if got := recorder.Header().Get("X-RateLimit-Remaining"); got != r.wantRemaining {
+					t.Errorf("request %d: X-RateLimit-Remaining = %q, want %q", i+1, got, r.wantRemaining)
+				}
+				if tt.limit == "off" {
+					if got := recorder.Header().Get("X-RateLimit-Limit"); got != "" {
+						t.Errorf("request %d: X-RateLimit-Limit = %q on an unlimited group", i+1, got)
+					}
+					continue
+				}
+				if got := recorder.Header().Get("X-RateLimit-Limit"); got != "2" {
+					t.Errorf("request %d: X-RateLimit-Limit = %q, want 2", i+1, got)
+				}
+
+				if r.wantStatus != http.StatusTooManyRequests {
+					continue
+				}
+				if code := errorCode(t, recorder); code != Errors.CodeRateLimited {
+					t.Errorf("request %d: code = %q, want rate_limited", i+1, code)
+				}
+				retryAfter, err := strconv.Atoi(recorder.Header().Get("Retry-After"))
+				if err != nil || retryAfter < 1 || retryAfter > 30 {
+					t.Errorf("request %d: Retry-After = %q, want 1 to 30 seconds", i+1, recorder.Header().Get("Retry-After"))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package Functions
+
+import (
+	"backend/Errors"
+	"backend/FunctionsHelper"
+	"backend/Repository"
+	"backend/Schemas"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Maximum length of a highlighted snippet, in characters
+const snippetLength = 160
+
+// Longest accepted search query
+const maxSearchQueryLength = 200
+
+type commentHighlight struct {
+	CommentID string `json:"comment_id"`
+	Username  string `json:"username"`
+	Snippet   string `json:"snippet"`
+}
+
+type searchResult struct {
+	Post     Schemas.Post       `json:"post"`
+	Score    float64            `json:"score"`
+	Problem  string             `json:"problem_highlight"`
+	Comments []commentHighlight `json:"comment_highlights"`
+}
+
+// SearchPosts ranks posts by how well their problem and comments match q.
+// Accepts the same "tags" filter and "limit" as GetAllPosts.
+func SearchPosts(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		Errors.Abort(c, Errors.Field("q", "q is required"))
+		return
+	}
+
+	if len(query) > maxSearchQueryLength {
+		Errors.Abort(c, Errors.Field("q", "q cannot exceed 200 characters"))
+		return
+	}
+
+	terms := Repository.SearchTerms(query)
+	if len(terms) == 0 {
+		Errors.Abort(c, Errors.Field("q", "q must contain at least one word"))
+		return
+	}
+
+	limit, err := parseLimit(c)
+	if err != nil {
+		Errors.Abort(c, err)
+		return
+	}
+
+	hits, err := Repository.Search().Search(c, Repository.SearchQuery{
+		Text:   query,
+		TagIDs: resolveTagFilter(c, c.Query("tags")),
+		Limit:  limit,
+	})
+	if err != nil {
+		Errors.Abort(c, Errors.Internal("Error searching posts", err))
+		return
+	}
+
+	// Build the highlighted snippets of every hit
+	results := make([]searchResult, 0, len(hits))
+	for _, hit := range hits {
+		result := searchResult{
+			Post:     hit.Post,
+			Score:    hit.Score,
+			Problem:  FunctionsHelper.Highlight(hit.Post.Problem, terms, snippetLength),
+			Comments: make([]commentHighlight, 0, len(hit.MatchedComments)),
+		}
+
+		for _, comment := range hit.MatchedComments {
+			result.Comments = append(result.Comments, commentHighlight{
+				CommentID: comment.ID.Hex(),
+				Username:  comment.Username,
+				Snippet:   FunctionsHelper.Highlight(comment.Description, terms, snippetLength),
+			})
+		}
+
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":   query,
+		"results": results,
+	})
+}
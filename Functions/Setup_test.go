@@ -0,0 +1,131 @@
+package Functions
+
+import (
+	"backend/Config"
+	"backend/Errors"
+	"backend/FunctionsHelper"
+	"backend/Mailer"
+	"backend/Repository"
+	"backend/Schemas"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	FunctionsHelper.SetJWTSecret("test-secret")
+}
+
+// setupTest gives the test empty in-memory repositories, the default
+// configuration and a fake AI provider, which it returns
+func setupTest(t *testing.T) *FunctionsHelper.FakeAIProvider {
+	t.Helper()
+
+	Repository.Use(Repository.NewMemoryRepositories())
+	Configure(Config.Default())
+	ai := &FunctionsHelper.FakeAIProvider{BlockedWords: []string{"badword"}}
+	FunctionsHelper.SetAIProvider(ai)
+	return ai
+}
+
+// newTestRouter returns a router answering errors like the server's
+func newTestRouter() *gin.Engine {
+	router := gin.New()
+	router.ContextWithFallback = true
+	router.Use(gin.CustomRecovery(Errors.Recovery), Errors.Middleware)
+	router.NoRoute(Errors.NoRoute)
+	return router
+}
+
+// createUser stores a user named name, changed by change when given, and
+// returns it with an access token
+func createUser(t *testing.T, name string, change func(user *Schemas.User)) (Schemas.User, string) {
+	t.Helper()
+
+	user := Schemas.User{Name: name, Email: name + "@example.com", Role: Schemas.RoleUser}
+	if change != nil {
+		change(&user)
+	}
+	id, err := Repository.Users().Create(context.Background(), user)
+	if err != nil {
+		t.Fatalf("creating user %s: %v", name, err)
+	}
+	user.ID = id
+
+	accessToken, _, err := FunctionsHelper.IssueTokenPair(user)
+	if err != nil {
+		t.Fatalf("issuing tokens for %s: %v", name, err)
+	}
+	return user, accessToken
+}
+
+// serve sends a request with an optional JSON body and bearer token
+func serve(router http.Handler, method string, path string, body string, token string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	request := httptest.NewRequest(method, path, reader)
+	if body != "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// errorCode returns the code of the error envelope in recorder, or "" when
+// the response is no error
+func errorCode(t *testing.T, recorder *httptest.ResponseRecorder) Errors.Code {
+	t.Helper()
+	return errorBody(t, recorder).Code
+}
+
+// errorBody decodes the error envelope in recorder
+func errorBody(t *testing.T, recorder *httptest.ResponseRecorder) Errors.Body {
+	t.Helper()
+
+	var envelope Errors.Envelope
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("decoding response %q: %v", recorder.Body.String(), err)
+	}
+	return envelope.Error
+}
+
+// useFileMailer makes the handlers mail into a FileMailer, which it returns
+func useFileMailer(t *testing.T) *Mailer.FileMailer {
+	t.Helper()
+
+	mailer := &Mailer.FileMailer{Dir: t.TempDir(), From: "test@example.com"}
+	Mailer.Set(mailer)
+	t.Cleanup(func() { Mailer.Set(nil) })
+	return mailer
+}
+
+// mailedToken returns the token of the link in message
+func mailedToken(t *testing.T, message Mailer.Message) string {
+	t.Helper()
+
+	match := regexp.MustCompile(`token=(\S+)`).FindStringSubmatch(message.Body)
+	if match == nil {
+		t.Fatalf("no token link in %q", message.Body)
+	}
+	token, err := url.QueryUnescape(match[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
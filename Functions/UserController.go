@@ -1,36 +1,54 @@
 package Functions
 
 import (
-	"backend/Mongo"
+	"backend/Errors"
+	"backend/FunctionsHelper"
+	"backend/Logging"
+	"backend/Repository"
 	"backend/Schemas"
+	"context"
+	"errors"
 	"net/http"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"golang.org/x/crypto/bcrypt"
 )
 
 func GetProfile(c *gin.Context) {
-	username := c.Query("username")
-	if username == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "Username required"})
-		return
-	}
-
-	client := Mongo.GetMongoDB()
-	var user Schemas.User
-	err := client.Database("tezno_district").Collection("users").FindOne(c, bson.M{"username": username}).Decode(&user)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
+	user, ok := CurrentUser(c)
+	if !ok {
+		Errors.Abort(c, Errors.Unauthorized("Authentication required"))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"username": user.Name,
-		"email":    user.Email,
+		"username":       user.Name,
+		"email":          user.Email,
+		"role":           roleOf(user),
+		"email_verified": !user.PendingVerification,
+	})
+}
+
+var (
+	dummyPasswordHash     []byte
+	dummyPasswordHashOnce sync.Once
+)
+
+// compareDummyPassword spends as long as checking a real password, so a
+// login for an unknown username takes as long as one with a wrong password
+func compareDummyPassword(password string) {
+	dummyPasswordHashOnce.Do(func() {
+		hash, err := bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+		if err != nil {
+			panic(err)
+		}
+		dummyPasswordHash = hash
 	})
+	bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
 }
 
 func Login(c *gin.Context) {
@@ -40,25 +58,40 @@ func Login(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&loginDetails); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request"})
+		Errors.Abort(c, Errors.InvalidBody(err))
 		return
 	}
 
-	client := Mongo.GetMongoDB()
-	var user Schemas.User
-	err := client.Database("tezno_district").Collection("users").FindOne(c, bson.M{"username": loginDetails.Username}).Decode(&user)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid username or password"})
+	throttle := newLoginThrottlePolicy(appConfig.Auth.Login)
+	keys := throttle.keys(c, loginDetails.Username)
+	if wait := throttle.retryAfter(c, keys, time.Now()); wait > 0 {
+		Errors.Abort(c, tooManyAttempts(c, wait))
 		return
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(loginDetails.Password))
+	user, err := Repository.Users().FindByUsername(c, loginDetails.Username)
+	if err == nil {
+		err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(loginDetails.Password))
+	} else {
+		compareDummyPassword(loginDetails.Password)
+		user = Schemas.User{Name: loginDetails.Username}
+	}
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid username or password"})
+		if wait := throttle.recordFailure(c, user, keys, time.Now()); wait > 0 {
+			Errors.Abort(c, tooManyAttempts(c, wait))
+			return
+		}
+		Errors.Abort(c, Errors.Unauthorized("Invalid username or password"))
 		return
 	}
+	throttle.resetUsername(c, keys)
 
-	c.JSON(http.StatusOK, gin.H{
+	if user.Banned {
+		Errors.Abort(c, Errors.Forbidden("Account is banned"))
+		return
+	}
+
+	respondWithTokens(c, user, gin.H{
 		"message": "Login successful",
 		"user":    user.Name,
 		"id":      user.ID.Hex(),
@@ -67,21 +100,36 @@ func Login(c *gin.Context) {
 
 // Register handles user registration
 func Register(c *gin.Context) {
-	var user Schemas.User
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request"})
+	// Only these fields come from the client, the ID, role, ban and
+	// verification state are the server's
+	var requestBody struct {
+		Name     string `json:"username"`
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		Errors.Abort(c, Errors.InvalidBody(err))
 		return
 	}
 
+	user := Schemas.User{
+		Name:     strings.TrimSpace(requestBody.Name),
+		Email:    strings.TrimSpace(requestBody.Email),
+		Password: requestBody.Password,
+		Role:     Schemas.RoleUser,
+		// New accounts stay restricted until the email address is confirmed
+		PendingVerification: true,
+	}
+
 	// Validate Name
-	if len(strings.TrimSpace(user.Name)) < 3 {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Name must be at least 3 characters long"})
+	if len(user.Name) < 3 {
+		Errors.Abort(c, Errors.Field("username", "Name must be at least 3 characters long"))
 		return
 	}
 
 	// Validate Email
 	if user.Email == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Email cannot be empty"})
+		Errors.Abort(c, Errors.Field("email", "Email cannot be empty"))
 		return
 	}
 
@@ -89,77 +137,157 @@ func Register(c *gin.Context) {
 	emailRegex := `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
 	re := regexp.MustCompile(emailRegex)
 	if !re.MatchString(user.Email) {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid email format"})
+		Errors.Abort(c, Errors.Field("email", "Invalid email format"))
 		return
 	}
 
-	// Validate Password
-	if len(user.Password) < 8 {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Password must be at least 8 characters long"})
+	// Usernames and emails are unique regardless of case
+	if err := ensureAvailable(c, "username", user.Name, Repository.Users().FindByUsername); err != nil {
+		Errors.Abort(c, err)
 		return
 	}
-
-	// Check if password contains at least one number
-	hasNumber := false
-	for _, c := range user.Password {
-		if c >= '0' && c <= '9' {
-			hasNumber = true
-			break
-		}
+	if err := ensureAvailable(c, "email", user.Email, Repository.Users().FindByEmail); err != nil {
+		Errors.Abort(c, err)
+		return
 	}
-	if !hasNumber {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Password must contain at least one number"})
+
+	// Validate Password
+	if err := FunctionsHelper.ValidatePassword(user.Password); err != nil {
+		Errors.Abort(c, Errors.Field("password", err.Error()))
 		return
 	}
 
 	// Hash the password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Error hashing password"})
+		Errors.Abort(c, Errors.Internal("Error hashing password", err))
 		return
 	}
 	user.Password = string(hashedPassword)
 
 	// Insert user into the database
-	client := Mongo.GetMongoDB()
-	_, err = client.Database("tezno_district").Collection("users").InsertOne(c, user)
+	user.ID, err = Repository.Users().Create(c, user)
+	var duplicate *Repository.DuplicateError
+	if errors.As(err, &duplicate) {
+		// Someone registered the same name or email in the meantime
+		Errors.Abort(c, takenError(duplicate.Field))
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Error registering user"})
+		Errors.Abort(c, Errors.Internal("Error registering user", err))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "User registered successfully"})
+	// A failed email is not fatal, the user can ask for another one
+	if err := sendVerificationEmail(c, user); err != nil {
+		Logging.FromContext(c).Error("Error sending verification email", "target_user", user.Name, "error", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User registered successfully, check your email to verify your address"})
 }
+
+// ensureAvailable fails with conflict when value is already used as field by
+// another account
+func ensureAvailable(c *gin.Context, field string, value string, find func(context.Context, string) (Schemas.User, error)) error {
+	_, err := find(c, value)
+	if errors.Is(err, Repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return Errors.Internal("Error registering user", err)
+	}
+	return takenError(field)
+}
+
+// takenError is the conflict of a taken username or email, as a field error
+func takenError(field string) error {
+	message := "Username is already taken"
+	if field == "email" {
+		message = "Email is already registered"
+	}
+	return Errors.Conflict(message).WithField(field, message)
+}
+
+// ChangePassword replaces the password of the authenticated user. The current
+// password is required, the new one must pass the registration policy and
+// every token issued before the change stops working.
 func ChangePassword(c *gin.Context) {
 	var changePassword struct {
-		Username    string `json:"username"`
-		NewPassword string `json:"newPassword"`
+		CurrentPassword string `json:"currentPassword"`
+		NewPassword     string `json:"newPassword"`
 	}
 
 	if err := c.ShouldBindJSON(&changePassword); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request"})
+		Errors.Abort(c, Errors.InvalidBody(err))
+		return
+	}
+
+	user, ok := CurrentUser(c)
+	if !ok {
+		Errors.Abort(c, Errors.Unauthorized("Authentication required"))
+		return
+	}
+
+	if changePassword.CurrentPassword == "" {
+		Errors.Abort(c, Errors.Field("currentPassword", "Current password is required"))
 		return
 	}
 
-	client := Mongo.GetMongoDB()
-	var user Schemas.User
-	err := client.Database("tezno_district").Collection("users").FindOne(c, bson.M{"username": changePassword.Username}).Decode(&user)
+	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(changePassword.CurrentPassword))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
+		recordSecurityEvent(c, user, Schemas.SecurityEventPasswordChangeFailed)
+		Errors.Abort(c, Errors.Unauthorized("Current password is incorrect"))
+		return
+	}
+
+	if err := FunctionsHelper.ValidatePassword(changePassword.NewPassword); err != nil {
+		Errors.Abort(c, Errors.Field("newPassword", err.Error()))
+		return
+	}
+
+	if changePassword.NewPassword == changePassword.CurrentPassword {
+		Errors.Abort(c, Errors.Field("newPassword", "New password must be different from the current one"))
 		return
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(changePassword.NewPassword), bcrypt.DefaultCost)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Error hashing password"})
+		Errors.Abort(c, Errors.Internal("Error hashing password", err))
 		return
 	}
 
-	_, err = client.Database("tezno_district").Collection("users").UpdateOne(c, bson.M{"username": changePassword.Username}, bson.M{"$set": bson.M{"password": string(hashedPassword)}})
+	// Also bumps the token version, signing out every other session
+	err = Repository.Users().UpdatePassword(c, user.ID, string(hashedPassword))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Error changing password"})
+		Errors.Abort(c, Errors.Internal("Error changing password", err))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
+	recordSecurityEvent(c, user, Schemas.SecurityEventPasswordChanged)
+
+	// Hand the caller fresh tokens so only this session stays signed in
+	updated, err := Repository.Users().FindByID(c, user.ID)
+	if err != nil {
+		Errors.Abort(c, Errors.Internal("Error changing password", err))
+		return
+	}
+	respondWithTokens(c, updated, gin.H{"message": "Password changed successfully"})
+}
+
+// recordSecurityEvent stores an audit record for user. Failing to record is
+// logged but never fails the request. The IP is only taken from
+// X-Forwarded-For when a trusted proxy set it.
+func recordSecurityEvent(c *gin.Context, user Schemas.User, eventType string) {
+	event := Schemas.SecurityEvent{
+		UserID:    user.ID,
+		Username:  user.Name,
+		Type:      eventType,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if err := Repository.SecurityEvents().Record(c, event); err != nil {
+		Logging.FromContext(c).Error("Error recording security event", "event", eventType, "target_user", user.Name, "error", err)
+	}
 }
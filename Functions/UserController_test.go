@@ -0,0 +1,205 @@
+package Functions
+
+import (
+	"backend/Errors"
+	"backend/FunctionsHelper"
+	"backend/Repository"
+	"backend/Schemas"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCode   Errors.Code
+		wantField  string
+	}{
+		{
+			name:       "registers",
+			body:       `{"username":"carol","email":"carol@example.com","password":"secret123"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "server fields are ignored",
+			body:       `{"ID":"000000000000000000000001","_id":"000000000000000000000001","username":"carol","email":"carol@example.com","password":"secret123","role":"admin","banned":true}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "taken username in other case",
+			body:       `{"username":"ALICE","email":"new@example.com","password":"secret123"}`,
+			wantStatus: http.StatusConflict,
+			wantCode:   Errors.CodeConflict,
+			wantField:  "username",
+		},
+		{
+			name:       "taken email in other case",
+			body:       `{"username":"carol","email":"Alice@Example.com","password":"secret123"}`,
+			wantStatus: http.StatusConflict,
+			wantCode:   Errors.CodeConflict,
+			wantField:  "email",
+		},
+		{
+			name:       "short username",
+			body:       `{"username":"al","email":"al@example.com","password":"secret123"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   Errors.CodeValidation,
+			wantField:  "username",
+		},
+		{
+			name:       "invalid email",
+			body:       `{"username":"carol","email":"carol@","password":"secret123"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   Errors.CodeValidation,
+			wantField:  "email",
+		},
+		{
+			name:       "weak password",
+			body:       `{"username":"carol","email":"carol@example.com","password":"secret"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   Errors.CodeValidation,
+			wantField:  "password",
+		},
+		{
+			name:       "wrong type",
+			body:       `{"username":7}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   Errors.CodeValidation,
+			wantField:  "username",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t)
+			createUser(t, "alice", nil)
+
+			router := newTestRouter()
+			router.POST("/register", Register)
+
+			recorder := serve(router, http.MethodPost, "/register", tt.body, "")
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+
+			if tt.wantCode != "" {
+				body := errorBody(t, recorder)
+				if body.Code != tt.wantCode {
+					t.Errorf("code = %q, want %q", body.Code, tt.wantCode)
+				}
+				if _, ok := body.Fields[tt.wantField]; !ok || len(body.Fields) != 1 {
+					t.Errorf("fields = %v, want only %s", body.Fields, tt.wantField)
+				}
+				return
+			}
+
+			user, err := Repository.Users().FindByUsername(context.Background(), "carol")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user.Role != Schemas.RoleUser || user.Banned || !user.PendingVerification {
+				t.Errorf("user = role %q, banned %v, pending %v, want an unverified plain user", user.Role, user.Banned, user.PendingVerification)
+			}
+			if user.ID.Hex() == "000000000000000000000001" {
+				t.Error("the client chose the user ID")
+			}
+			if user.Password == "secret123" {
+				t.Error("the password is stored in plain text")
+			}
+		})
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCode   Errors.Code
+		wantEvent  string
+	}{
+		{"changes", `{"currentPassword":"secret123","newPassword":"better456"}`, http.StatusOK, "", Schemas.SecurityEventPasswordChanged},
+		{"wrong current password", `{"currentPassword":"guess123","newPassword":"better456"}`, http.StatusUnauthorized, Errors.CodeUnauthorized, Schemas.SecurityEventPasswordChangeFailed},
+		{"missing current password", `{"newPassword":"better456"}`, http.StatusBadRequest, Errors.CodeValidation, ""},
+		{"weak new password", `{"currentPassword":"secret123","newPassword":"short"}`, http.StatusBadRequest, Errors.CodeValidation, ""},
+		{"same password", `{"currentPassword":"secret123","newPassword":"secret123"}`, http.StatusBadRequest, Errors.CodeValidation, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t)
+			user, accessToken := createUser(t, "alice", func(user *Schemas.User) { user.Password = string(hash) })
+			_, refreshToken, err := FunctionsHelper.IssueTokenPair(user)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			router := newTestRouter()
+			router.GET("/profile", AuthRequired, GetProfile)
+			router.POST("/changePassword", AuthRequired, ChangePassword)
+			router.POST("/token/refresh", RefreshToken)
+			router.POST("/login", Login)
+
+			recorder := serve(router, http.MethodPost, "/changePassword", tt.body, accessToken)
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+			if tt.wantCode != "" {
+				if code := errorCode(t, recorder); code != tt.wantCode {
+					t.Errorf("code = %q, want %q", code, tt.wantCode)
+				}
+			}
+
+			events, err := Repository.SecurityEvents().FindByUser(context.Background(), user.ID, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantEvent == "" && len(events) != 0 || tt.wantEvent != "" && (len(events) != 1 || events[0].Type != tt.wantEvent) {
+				t.Errorf("events = %+v, want %q", events, tt.wantEvent)
+			}
+
+			changed := tt.wantStatus == http.StatusOK
+			oldTokenStatus := http.StatusOK
+			if changed {
+				oldTokenStatus = http.StatusUnauthorized
+			}
+			if recorder := serve(router, http.MethodGet, "/profile", "", accessToken); recorder.Code != oldTokenStatus {
+				t.Errorf("old access token: status = %d, want %d", recorder.Code, oldTokenStatus)
+			}
+			if recorder := serve(router, http.MethodPost, "/token/refresh", `{"refresh_token":"`+refreshToken+`"}`, ""); recorder.Code != oldTokenStatus {
+				t.Errorf("old refresh token: status = %d, want %d", recorder.Code, oldTokenStatus)
+			}
+			if !changed {
+				return
+			}
+
+			// The caller gets fresh tokens, and only the new password logs in
+			var tokens struct {
+				AccessToken string `json:"access_token"`
+			}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &tokens); err != nil {
+				t.Fatal(err)
+			}
+			if recorder := serve(router, http.MethodGet, "/profile", "", tokens.AccessToken); recorder.Code != http.StatusOK {
+				t.Errorf("new access token: status = %d, want 200", recorder.Code)
+			}
+			if recorder := serve(router, http.MethodPost, "/login", `{"username":"alice","password":"secret123"}`, ""); recorder.Code != http.StatusUnauthorized {
+				t.Errorf("login with the old password: status = %d, want 401", recorder.Code)
+			}
+			if recorder := serve(router, http.MethodPost, "/login", `{"username":"alice","password":"better456"}`, ""); recorder.Code != http.StatusOK {
+				t.Errorf("login with the new password: status = %d, want 200", recorder.Code)
+			}
+		})
+	}
+}
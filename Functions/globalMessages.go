@@ -1,15 +1,31 @@
 package Functions
 
 import (
+	"backend/Chat"
+	"backend/Errors"
 	"backend/FunctionsHelper"
-	"log"
+	"backend/Logging"
+	"backend/Repository"
+	"backend/Schemas"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"net/http"
-	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Number of past messages sent to a client when it joins a room
+const chatReplayCount = 50
+
+// Longest handling one incoming chat message, AI check included, may take.
+// The connection's own context lasts as long as the connection.
+const chatMessageTimeout = 30 * time.Second
+
 // WebSocket upgrader
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
@@ -17,20 +33,19 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-// ChatRoom structure
-type ChatRoom struct {
-	Name      string
-	Clients   map[*websocket.Conn]bool
-	Broadcast chan Message
-}
+// Live chatrooms and their connected clients
+var chatHub = Chat.NewHub()
 
-var rooms = make(map[string]*ChatRoom) // Stores all chatrooms
-var roomsMu sync.Mutex                 // Mutex for thread-safe room operations
+// ShutdownChat disconnects every chat client and waits for their last
+// messages to be handled, or until ctx is done
+func ShutdownChat(ctx context.Context) error {
+	return chatHub.Shutdown(ctx)
+}
 
-// Message structure
+// Message structure sent by clients. The sender is always the authenticated
+// user of the connection.
 type Message struct {
-	Username string `json:"username"`
-	Content  string `json:"content"`
+	Content string `json:"content"`
 }
 
 // Create a new chatroom
@@ -41,132 +56,263 @@ func CreateRoom(c *gin.Context) {
 
 	// Parse the request body
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room name"})
+		Errors.Abort(c, Errors.InvalidBody(err))
 		return
 	}
 
 	if req.RoomName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Room name cannot be empty"})
+		Errors.Abort(c, Errors.Field("room_name", "Room name cannot be empty"))
 		return
 	}
 
-	// Check if the room already exists
-	roomsMu.Lock()
-	if _, exists := rooms[req.RoomName]; exists {
-		roomsMu.Unlock()
-		c.JSON(http.StatusConflict, gin.H{"error": "Room already exists"})
-		return
-	}
+	user, _ := CurrentUser(c)
 
-	// Create the new room
-	room := &ChatRoom{
+	// Persist the room, names are unique
+	_, err := Repository.Rooms().Create(c, Schemas.ChatRoom{
 		Name:      req.RoomName,
-		Clients:   make(map[*websocket.Conn]bool),
-		Broadcast: make(chan Message),
+		CreatedBy: user.Name,
+		CreatedAt: time.Now().UTC(),
+	})
+	if errors.Is(err, Repository.ErrDuplicate) {
+		Errors.Abort(c, Errors.Conflict("Room already exists"))
+		return
+	}
+	if err != nil {
+		Errors.Abort(c, Errors.Internal("Error creating room", err))
+		return
 	}
-	rooms[req.RoomName] = room
-	roomsMu.Unlock()
-
-	// Start broadcasting messages for this room
-	go broadcastRoomMessages(room)
 
 	c.JSON(http.StatusCreated, gin.H{"message": "Room created successfully", "room_name": req.RoomName})
 }
 
 // Get a list of all available chatrooms
 func GetAllRooms(c *gin.Context) {
-	roomsMu.Lock()
-	defer roomsMu.Unlock()
+	storedRooms, err := Repository.Rooms().FindAll(c)
+	if err != nil {
+		Errors.Abort(c, Errors.Internal("Error retrieving rooms", err))
+		return
+	}
 
 	// Collect room names
-	var roomNames []string
-	for roomName := range rooms {
-		roomNames = append(roomNames, roomName)
+	roomNames := make([]string, 0, len(storedRooms))
+	for _, room := range storedRooms {
+		roomNames = append(roomNames, room.Name)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"rooms": roomNames})
 }
 
-// Handle WebSocket connections for a specific room
+// DeleteRoom deletes a chatroom with its history and disconnects its clients
+func DeleteRoom(c *gin.Context) {
+	roomName := c.Param("name")
+
+	deleted, err := Repository.Rooms().Delete(c, roomName)
+	if err != nil {
+		Errors.Abort(c, Errors.Internal("Error deleting room", err))
+		return
+	}
+	if !deleted {
+		Errors.Abort(c, Errors.NotFound("Room not found"))
+		return
+	}
+
+	chatHub.Close(roomName)
+
+	removed, err := Repository.Messages().DeleteByRoom(c, roomName)
+	if err != nil {
+		Logging.FromContext(c).Error("Error deleting room history", "room", roomName, "error", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Room deleted successfully", "deleted_messages": removed})
+}
+
+// GetRoomMessages returns a page of a room's history, oldest first. "before"
+// is a message ID or an RFC 3339 timestamp, "limit" defaults to 20.
+func GetRoomMessages(c *gin.Context) {
+	roomName := c.Param("name")
+	if _, err := Repository.Rooms().FindByName(c, roomName); err != nil {
+		Errors.Abort(c, Errors.NotFound("Room not found"))
+		return
+	}
+
+	var before primitive.ObjectID
+	if beforeParam := c.Query("before"); beforeParam != "" {
+		if id, err := primitive.ObjectIDFromHex(beforeParam); err == nil {
+			before = id
+		} else if timestamp, err := time.Parse(time.RFC3339, beforeParam); err == nil {
+			before = firstObjectIDAt(timestamp)
+		} else {
+			Errors.Abort(c, Errors.Field("before", "before must be a message ID or an RFC 3339 timestamp"))
+			return
+		}
+	}
+
+	limit, err := parseLimit(c)
+	if err != nil {
+		Errors.Abort(c, err)
+		return
+	}
+
+	messages, err := Repository.Messages().FindBefore(c, roomName, before, limit)
+	if err != nil {
+		Errors.Abort(c, Errors.Internal("Error retrieving messages", err))
+		return
+	}
+
+	// The oldest message of a full page is where the next page starts
+	nextBefore := ""
+	if len(messages) == limit {
+		nextBefore = messages[0].ID.Hex()
+	}
+
+	c.JSON(http.StatusOK, gin.H{"messages": messages, "next_before": nextBefore})
+}
+
+// firstObjectIDAt returns the lowest ObjectID of the second t falls in, so
+// every message sent at or after t sorts after it. NewObjectIDFromTimestamp
+// fills in a counter and would split the messages of that second.
+func firstObjectIDAt(t time.Time) primitive.ObjectID {
+	var id primitive.ObjectID
+	binary.BigEndian.PutUint32(id[:4], uint32(t.Unix()))
+	return id
+}
+
+// liveRoom returns the live room for name, provided it has been created
+func liveRoom(ctx context.Context, name string) (*Chat.Room, error) {
+	if _, err := Repository.Rooms().FindByName(ctx, name); err != nil {
+		return nil, err
+	}
+	return chatHub.Room(name), nil
+}
+
+// checkSender reloads the sender of a chat connection and reports why it may
+// no longer send, nil when it still may
+func checkSender(ctx context.Context, user Schemas.User) *Errors.Error {
+	current, err := Repository.Users().FindByID(ctx, user.ID)
+	if errors.Is(err, Repository.ErrNotFound) {
+		return Errors.Unauthorized("User no longer exists")
+	}
+	if err != nil {
+		Logging.FromContext(ctx).Error("Error loading chat sender", "error", err)
+		return Errors.Internal("Error loading user", err)
+	}
+	if current.TokenVersion != user.TokenVersion {
+		return Errors.Unauthorized("Token has been revoked")
+	}
+	if current.Banned {
+		return Errors.Forbidden("Account is banned")
+	}
+	return nil
+}
+
+// Handle WebSocket connections for a specific room. Anyone may listen, only
+// clients that connected with an access token (see OptionalAuth) may send.
 func HandleConnections(c *gin.Context) {
 	// Get the room name from the query parameter
 	roomName := c.Query("room")
 	if roomName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Room name is required"})
+		Errors.Abort(c, Errors.Field("room", "Room name is required"))
 		return
 	}
 
 	// Check if the room exists
-	roomsMu.Lock()
-	room, exists := rooms[roomName]
-	roomsMu.Unlock()
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+	room, err := liveRoom(c, roomName)
+	if errors.Is(err, Repository.ErrNotFound) {
+		Errors.Abort(c, Errors.NotFound("Room not found"))
+		return
+	}
+	if err != nil {
+		Errors.Abort(c, Errors.Internal("Error loading room", err))
 		return
 	}
 
 	// Upgrade HTTP request to WebSocket
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
-		log.Println("Error upgrading to WebSocket:", err)
+		Logging.FromContext(c).Warn("Error upgrading to WebSocket", "room", roomName, "error", err)
 		return
 	}
-	defer conn.Close()
 
-	// Register client in the room
-	roomsMu.Lock()
-	room.Clients[conn] = true
-	roomsMu.Unlock()
+	// Replay recent history before any new broadcast reaches the client. A
+	// message sent while the history loads can be missed, which is cheaper
+	// than holding up every broadcast of the room for the query.
+	history, err := Repository.Messages().FindBefore(c.Request.Context(), roomName, primitive.NilObjectID, chatReplayCount)
+	if err != nil {
+		Logging.FromContext(c).Error("Error loading room history", "room", roomName, "error", err)
+	}
+	replay := make([]interface{}, len(history))
+	for i, msg := range history {
+		replay[i] = msg
+	}
+	client := room.Join(conn, replay)
 
-	// Read messages from the client
-	for {
-		var msg Message
-		err := conn.ReadJSON(&msg)
-		if err != nil {
-			log.Printf("Error reading message: %v", err)
-			roomsMu.Lock()
-			delete(room.Clients, conn)
-			roomsMu.Unlock()
-			break
+	// Every message costs an AI check, so senders are limited per user
+	user, authenticated := CurrentUser(c)
+	limiter, limited := newRateLimiter(RateLimitChat)
+	subject := rateLimitSubject(c)
+
+	// Read messages from the client until it disconnects
+	client.ReadLoop(func(data []byte) {
+		var incoming Message
+		if err := json.Unmarshal(data, &incoming); err != nil {
+			Logging.FromContext(c).Warn("Invalid chat message", "room", roomName, "error", err)
+			client.Send(Errors.InvalidBody(err).Envelope(c))
+			return
 		}
 
-		// Check the message content with AI
-		isAppropriate, err := FunctionsHelper.CallAIService(msg.Content, 1, "You are a bot that checks if the post is appropriate or not. By appropriate it is meant there are bad words. If it is appropriate return 1; else return 0.")
-		if err != nil {
-			log.Printf("AI check error: %v", err)
-			continue
+		ctx, cancel := context.WithTimeout(c.Request.Context(), chatMessageTimeout)
+		defer cancel()
+
+		if !authenticated {
+			client.Send(Errors.Unauthorized("Log in to send messages").Envelope(c))
+			return
 		}
 
-		if isAppropriate == "1" {
-			// Add to the room's broadcast channel if appropriate
-			room.Broadcast <- msg
-		} else {
-			// Send a hidden message for moderation
-			log.Printf("Message blocked by AI: %s", msg.Content)
-			hiddenMessage := Message{
-				Username: msg.Username,
-				Content:  "This message was hidden by AI moderation.",
+		if limited {
+			if state, _ := limiter.take(ctx, subject); !state.Allowed {
+				client.Send(Errors.RateLimited("Too many messages, slow down", state.RetryAfter).Envelope(c))
+				return
 			}
-			room.Broadcast <- hiddenMessage
 		}
-	}
-}
 
-// Broadcast messages to all clients in a room
-func broadcastRoomMessages(room *ChatRoom) {
-	for {
-		msg := <-room.Broadcast
-
-		// Send message to all connected clients in the room
-		roomsMu.Lock()
-		for client := range room.Clients {
-			err := client.WriteJSON(msg)
-			if err != nil {
-				log.Printf("Error broadcasting to client: %v", err)
+		// The connection outlives its token, a ban or password change since
+		// it opened closes it
+		if err := checkSender(ctx, user); err != nil {
+			client.Send(err.Envelope(c))
+			if err.Code != Errors.CodeInternal {
 				client.Close()
-				delete(room.Clients, client)
 			}
+			return
 		}
-		roomsMu.Unlock()
-	}
+
+		// Check the message content with AI
+		isAppropriate, err := FunctionsHelper.IsContentAppropriate(ctx, incoming.Content)
+		if err != nil {
+			Logging.FromContext(c).Error("Error moderating chat message", "room", roomName, "ai_provider", FunctionsHelper.GetAIProvider().Name(), "error", err)
+			client.Send(Errors.AIUnavailable(err).Envelope(c))
+			return
+		}
+
+		msg := Schemas.ChatMessage{
+			Room:     roomName,
+			UserID:   user.ID,
+			Username: user.Name,
+			Content:  incoming.Content,
+			SentAt:   time.Now().UTC(),
+		}
+		if !isAppropriate {
+			// Send a hidden message for moderation
+			Logging.FromContext(c).Info("Chat message hidden by AI moderation", "room", roomName, "ai_provider", FunctionsHelper.GetAIProvider().Name())
+			msg.Content = "This message was hidden by AI moderation."
+			msg.Hidden = true
+		}
+
+		// Persist before broadcasting so history and live clients agree
+		msg.ID, err = Repository.Messages().Create(ctx, msg)
+		if err != nil {
+			Logging.FromContext(c).Error("Error saving chat message", "room", roomName, "error", err)
+		}
+
+		room.Broadcast(msg)
+	})
 }
@@ -0,0 +1,277 @@
+package Functions
+
+import (
+	"backend/Errors"
+	"backend/Repository"
+	"backend/Schemas"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// chatFrame is either a chat message or an error envelope
+type chatFrame struct {
+	Schemas.ChatMessage
+	Error *Errors.Body `json:"error"`
+}
+
+// startChat serves the chat WebSocket of a new room "general"
+func startChat(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	if _, err := Repository.Rooms().Create(context.Background(), Schemas.ChatRoom{Name: "general"}); err != nil {
+		t.Fatal(err)
+	}
+	router := newTestRouter()
+	router.GET("/ws", OptionalAuth, HandleConnections)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// dialChat joins "general", with an access token when token is set
+func dialChat(t *testing.T, server *httptest.Server, token string) *websocket.Conn {
+	t.Helper()
+
+	query := url.Values{"room": {"general"}}
+	if token != "" {
+		query.Set("token", token)
+	}
+	address := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?" + query.Encode()
+	conn, _, err := websocket.DefaultDialer.Dial(address, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readFrame waits for the next frame, failing when the connection closes
+func readFrame(t *testing.T, conn *websocket.Conn) chatFrame {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var frame chatFrame
+	if err := conn.ReadJSON(&frame); err != nil {
+		t.Fatalf("reading frame: %v", err)
+	}
+	return frame
+}
+
+func TestChatSend(t *testing.T) {
+	tests := []struct {
+		name        string
+		anonymous   bool
+		change      func(user Schemas.User) error
+		aiErr       error
+		content     string
+		wantCode    Errors.Code // Empty for a broadcast message
+		wantContent string
+		wantHidden  bool
+		wantClosed  bool
+	}{
+		{
+			name:        "sends",
+			content:     "hello",
+			wantContent: "hello",
+		},
+		{
+			name:        "moderation hides",
+			content:     "a badword here",
+			wantContent: "This message was hidden by AI moderation.",
+			wantHidden:  true,
+		},
+		{
+			name:      "anonymous listeners cannot send",
+			anonymous: true,
+			content:   "hello",
+			wantCode:  Errors.CodeUnauthorized,
+		},
+		{
+			name:     "AI outage is reported",
+			aiErr:    errors.New("provider down"),
+			content:  "hello",
+			wantCode: Errors.CodeAIUnavailable,
+		},
+		{
+			name:     "invalid message is reported",
+			content:  "not json",
+			wantCode: Errors.CodeValidation,
+		},
+		{
+			name: "banned since connecting",
+			change: func(user Schemas.User) error {
+				return Repository.Users().SetBanned(context.Background(), user.ID, true)
+			},
+			content:    "hello",
+			wantCode:   Errors.CodeForbidden,
+			wantClosed: true,
+		},
+		{
+			name: "password changed since connecting",
+			change: func(user Schemas.User) error {
+				return Repository.Users().UpdatePassword(context.Background(), user.ID, "new hash")
+			},
+			content:    "hello",
+			wantCode:   Errors.CodeUnauthorized,
+			wantClosed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ai := setupTest(t)
+			ai.Err = tt.aiErr
+			user, token := createUser(t, "alice", nil)
+			if tt.anonymous {
+				token = ""
+			}
+
+			conn := dialChat(t, startChat(t), token)
+			if tt.change != nil {
+				if err := tt.change(user); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			data := tt.content
+			if tt.content != "not json" {
+				encoded, _ := json.Marshal(Message{Content: tt.content})
+				data = string(encoded)
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(data)); err != nil {
+				t.Fatal(err)
+			}
+
+			frame := readFrame(t, conn)
+			if tt.wantCode != "" {
+				if frame.Error == nil || frame.Error.Code != tt.wantCode {
+					t.Fatalf("frame = %+v, want a %s error", frame, tt.wantCode)
+				}
+			} else {
+				if frame.Error != nil {
+					t.Fatalf("frame = %+v, want a message", frame.Error)
+				}
+				if frame.Content != tt.wantContent || frame.Hidden != tt.wantHidden || frame.UserID != user.ID {
+					t.Errorf("message = %+v, want %q hidden %v from %s", frame.ChatMessage, tt.wantContent, tt.wantHidden, user.Name)
+				}
+			}
+
+			stored, err := Repository.Messages().FindBefore(context.Background(), "general", primitive.NilObjectID, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if wantStored := tt.wantCode == ""; (len(stored) == 1) != wantStored {
+				t.Errorf("stored %d messages, want stored %v", len(stored), wantStored)
+			}
+
+			if tt.wantClosed {
+				conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+				if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNoStatusReceived, websocket.CloseNormalClosure) {
+					t.Errorf("read after revocation = %v, want the connection closed", err)
+				}
+			}
+		})
+	}
+}
+
+func TestGetRoomMessages(t *testing.T) {
+	setupTest(t)
+	ctx := context.Background()
+
+	if _, err := Repository.Rooms().Create(ctx, Schemas.ChatRoom{Name: "general"}); err != nil {
+		t.Fatal(err)
+	}
+	// Five messages a minute apart, their IDs ordered like their times
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	sender := primitive.NewObjectID()
+	ids := make([]string, 5)
+	for i := range ids {
+		sentAt := start.Add(time.Duration(i) * time.Minute)
+		id, err := Repository.Messages().Create(ctx, Schemas.ChatMessage{
+			ID:       primitive.NewObjectIDFromTimestamp(sentAt),
+			Room:     "general",
+			UserID:   sender,
+			Username: "alice",
+			Content:  fmt.Sprintf("message %d", i),
+			SentAt:   sentAt,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = id.Hex()
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		wantContents   []string
+		wantNextBefore string
+	}{
+		{"newest page", "?limit=2", []string{"message 3", "message 4"}, ids[3]},
+		{"next page", "?limit=2&before=" + ids[3], []string{"message 1", "message 2"}, ids[1]},
+		{"last page", "?limit=2&before=" + ids[1], []string{"message 0"}, ""},
+		{"before a time", "?limit=5&before=" + start.Add(2*time.Minute).Format(time.RFC3339), []string{"message 0", "message 1"}, ""},
+		{"everything", "", []string{"message 0", "message 1", "message 2", "message 3", "message 4"}, ""},
+	}
+
+	router := newTestRouter()
+	router.GET("/rooms/:name/messages", GetRoomMessages)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := serve(router, http.MethodGet, "/rooms/general/messages"+tt.query, "", "")
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", recorder.Code, recorder.Body)
+			}
+
+			var page struct {
+				Messages   []Schemas.ChatMessage `json:"messages"`
+				NextBefore string                `json:"next_before"`
+			}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &page); err != nil {
+				t.Fatal(err)
+			}
+			contents := make([]string, 0, len(page.Messages))
+			for _, message := range page.Messages {
+				contents = append(contents, message.Content)
+			}
+			if !reflect.DeepEqual(contents, tt.wantContents) {
+				t.Errorf("messages = %v, want %v", contents, tt.wantContents)
+			}
+			if page.NextBefore != tt.wantNextBefore {
+				t.Errorf("next_before = %q, want %q", page.NextBefore, tt.wantNextBefore)
+			}
+		})
+	}
+
+	errorTests := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{"invalid before", "/rooms/general/messages?before=yesterday", http.StatusBadRequest},
+		{"missing room", "/rooms/nowhere/messages", http.StatusNotFound},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := serve(router, http.MethodGet, tt.path, "", "")
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+		})
+	}
+}
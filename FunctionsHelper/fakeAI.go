@@ -0,0 +1,65 @@
+package FunctionsHelper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// FakeAIProvider is a deterministic, offline provider. Moderation requests
+// are rejected when the text contains one of BlockedWords, every other prompt
+// gets a canned answer derived from the question. All calls are recorded.
+type FakeAIProvider struct {
+	BlockedWords []string
+	// Err, when set, is returned from every call to simulate an outage
+	Err error
+
+	mu    sync.Mutex
+	calls []FakeAICall
+}
+
+// FakeAICall is a single recorded call to FakeAIProvider
+type FakeAICall struct {
+	SystemPrompt string
+	Question     string
+	MaxTokens    int
+}
+
+func (p *FakeAIProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeAIProvider) Complete(ctx context.Context, systemPrompt string, question string, maxTokens int) (string, error) {
+	p.mu.Lock()
+	p.calls = append(p.calls, FakeAICall{SystemPrompt: systemPrompt, Question: question, MaxTokens: maxTokens})
+	p.mu.Unlock()
+
+	if p.Err != nil {
+		return "", p.Err
+	}
+
+	if systemPrompt == ModerationPrompt {
+		lowered := strings.ToLower(question)
+		for _, word := range p.BlockedWords {
+			if word != "" && strings.Contains(lowered, strings.ToLower(word)) {
+				return "0", nil
+			}
+		}
+		return "1", nil
+	}
+
+	answer := fmt.Sprintf("Fake answer to: %s", question)
+	if maxTokens > 0 && len(answer) > maxTokens*4 {
+		answer = answer[:maxTokens*4]
+	}
+	return answer, nil
+}
+
+// Calls returns a copy of every call made so far
+func (p *FakeAIProvider) Calls() []FakeAICall {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]FakeAICall(nil), p.calls...)
+}
@@ -0,0 +1,84 @@
+package FunctionsHelper
+
+import (
+	"backend/Config"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFakeAIModeration(t *testing.T) {
+	outage := errors.New("provider down")
+
+	tests := []struct {
+		name        string
+		provider    *FakeAIProvider
+		text        string
+		wantAllowed bool
+		wantErr     error
+	}{
+		{"approves", &FakeAIProvider{BlockedWords: []string{"badword"}}, "A friendly question", true, nil},
+		{"rejects", &FakeAIProvider{BlockedWords: []string{"badword"}}, "Some badword here", false, nil},
+		{"ignores case", &FakeAIProvider{BlockedWords: []string{"BadWord"}}, "some BADWORD here", false, nil},
+		{"empty words block nothing", &FakeAIProvider{BlockedWords: []string{""}}, "anything", true, nil},
+		{"outage", &FakeAIProvider{Err: outage}, "A friendly question", false, outage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetAIProvider(tt.provider)
+
+			allowed, err := IsContentAppropriate(context.Background(), tt.text)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if allowed != tt.wantAllowed {
+				t.Errorf("allowed = %v, want %v", allowed, tt.wantAllowed)
+			}
+
+			calls := tt.provider.Calls()
+			if len(calls) != 1 || calls[0].SystemPrompt != ModerationPrompt || calls[0].Question != tt.text || calls[0].MaxTokens != 1 {
+				t.Errorf("calls = %+v, want one moderation call", calls)
+			}
+		})
+	}
+}
+
+func TestFakeAIAnswer(t *testing.T) {
+	tests := []struct {
+		name      string
+		question  string
+		maxTokens int
+		want      string
+	}{
+		{"answers", "How do I center a div?", 50, "Fake answer to: How do I center a div?"},
+		{"unlimited", "Why?", 0, "Fake answer to: Why?"},
+		{"truncates to about four bytes a token", strings.Repeat("x", 100), 5, "Fake answer to: xxxx"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &FakeAIProvider{}
+			answer, err := provider.Complete(context.Background(), AnswerPrompt, tt.question, tt.maxTokens)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if answer != tt.want {
+				t.Errorf("answer = %q, want %q", answer, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewAIProviderFake(t *testing.T) {
+	provider := NewAIProvider(Config.AI{Provider: "fake", FakeBlockedWords: []string{"spam"}})
+
+	fake, ok := provider.(*FakeAIProvider)
+	if !ok {
+		t.Fatalf("provider = %T, want *FakeAIProvider", provider)
+	}
+	if fake.Name() != "fake" || len(fake.BlockedWords) != 1 || fake.BlockedWords[0] != "spam" {
+		t.Errorf("provider = %+v, want the configured blocked words", fake)
+	}
+}
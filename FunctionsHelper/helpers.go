@@ -1,79 +1,114 @@
 package FunctionsHelper
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
-	"log"
-	"net/http"
+	"backend/Config"
+	"backend/Logging"
+	"backend/Metrics"
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
 )
 
-// CallAIService sends a request to the OpenAI API and returns the response.
-func CallAIService(question string, responseLength int, gptRple string) (string, error) {
-	apiURL := "https://api.openai.com/v1/chat/completions"
-	apiKey := "api"
-
-	requestBody := map[string]interface{}{
-		"model": "gpt-4o-mini",
-		"store": true,
-		"messages": []map[string]string{
-			{"role": "system", "content": gptRple},
-			{"role": "user", "content": question},
-		},
-		"max_tokens": responseLength,
-	}
-	jsonBody, err := json.Marshal(requestBody)
-	if err != nil {
-		log.Printf("Error marshaling request body: %v", err)
-		return "", fmt.Errorf("failed to marshal request body: %v", err)
-	}
+// Prompts shared by every handler that talks to the AI provider
+const (
+	ModerationPrompt = "You are a bot that checks if the post is appropriate or not. By appropriate it is meant there are bad words. If it is appropriate return 1; else return 0."
+	AnswerPrompt     = "You are an AI assistant for a Q&A site. Your purpose is to provide the first helpful and concise answer to users' questions. There is no followup. There is just your answer and it is not posible to ask for more information."
+	SummaryPrompt    = "Summarize the following post and its comments concisely:"
+)
 
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		log.Printf("Error creating request: %v", err)
-		return "", fmt.Errorf("failed to create request: %v", err)
-	}
+// AIProvider is implemented by every backend able to answer a single
+// system + user prompt chat completion.
+type AIProvider interface {
+	// Name identifies the provider in logs
+	Name() string
+	// Complete returns the model's answer to question, limited to maxTokens
+	Complete(ctx context.Context, systemPrompt string, question string, maxTokens int) (string, error)
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
+var (
+	aiProvider   AIProvider
+	aiProviderMu sync.Mutex
+)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("HTTP request failed: %v", err)
-		return "", fmt.Errorf("request failed: %v", err)
-	}
-	defer resp.Body.Close()
+// GetAIProvider returns the configured provider, the default configuration's
+// when main has not set one.
+func GetAIProvider() AIProvider {
+	aiProviderMu.Lock()
+	defer aiProviderMu.Unlock()
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := ioutil.ReadAll(resp.Body)
-		log.Printf("OpenAI API error response: %s", string(bodyBytes))
-		return "", fmt.Errorf("API call failed with status %d: %s", resp.StatusCode, string(bodyBytes))
-	}
-	var responseData map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&responseData); err != nil {
-		log.Printf("Error decoding response: %v", err)
-		return "", fmt.Errorf("failed to decode response: %v", err)
+	if aiProvider == nil {
+		aiProvider = instrumentedProvider{NewAIProvider(Config.Default().AI)}
 	}
+	return aiProvider
+}
+
+// SetAIProvider replaces the provider used by the handlers, e.g. with a
+// FakeAIProvider when running offline.
+func SetAIProvider(provider AIProvider) {
+	aiProviderMu.Lock()
+	defer aiProviderMu.Unlock()
 
-	choices, ok := responseData["choices"].([]interface{})
-	if !ok || len(choices) == 0 {
-		log.Printf("Unexpected response structure: %+v", responseData)
-		return "", fmt.Errorf("no choices found in response")
+	aiProvider = instrumentedProvider{provider}
+}
+
+// instrumentedProvider records the count, latency and outcome of every call
+type instrumentedProvider struct {
+	AIProvider
+}
+
+func (p instrumentedProvider) Complete(ctx context.Context, systemPrompt string, question string, maxTokens int) (string, error) {
+	start := time.Now()
+	answer, err := p.AIProvider.Complete(ctx, systemPrompt, question, maxTokens)
+
+	latency := time.Since(start)
+
+	Metrics.AICallDuration.WithLabelValues(p.Name()).Observe(latency.Seconds())
+	Metrics.AICalls.WithLabelValues(p.Name(), Metrics.Outcome(err)).Inc()
+
+	// A failure is logged once, by whoever reports it: Errors.Middleware
+	// for requests, the read loop for chat messages
+	Logging.FromContext(ctx).Debug("AI call", "ai_provider", p.Name(), "latency_ms", latency.Milliseconds(), "outcome", Metrics.Outcome(err))
+	return answer, err
+}
+
+// NewAIProvider creates the provider named by cfg (openai, ollama or fake).
+// An empty base URL or model selects the provider's default.
+func NewAIProvider(cfg Config.AI) AIProvider {
+	switch cfg.Provider {
+	case "ollama":
+		return &OllamaProvider{
+			BaseURL: orDefault(cfg.BaseURL, "http://localhost:11434"),
+			Model:   orDefault(cfg.Model, "llama3.2"),
+		}
+	case "fake":
+		return &FakeAIProvider{BlockedWords: cfg.FakeBlockedWords}
+	case "openai":
+	default:
+		slog.Warn("Unknown AI provider, falling back to openai", "ai_provider", cfg.Provider)
 	}
 
-	message, ok := choices[0].(map[string]interface{})["message"].(map[string]interface{})
-	if !ok {
-		log.Printf("Message structure missing in response: %+v", choices[0])
-		return "", fmt.Errorf("message structure not found in choices")
+	return &OpenAIProvider{
+		BaseURL: orDefault(cfg.BaseURL, "https://api.openai.com/v1"),
+		APIKey:  cfg.APIKey,
+		Model:   orDefault(cfg.Model, "gpt-4o-mini"),
 	}
+}
 
-	content, ok := message["content"].(string)
-	if !ok {
-		log.Printf("Content missing in message: %+v", message)
-		return "", fmt.Errorf("content not found in message")
+func orDefault(value string, fallback string) string {
+	if value == "" {
+		return fallback
 	}
+	return value
+}
 
-	return content, nil
+// IsContentAppropriate asks the AI provider to moderate text. Only an explicit
+// "0" from the model marks the content as inappropriate.
+func IsContentAppropriate(ctx context.Context, text string) (bool, error) {
+	response, err := GetAIProvider().Complete(ctx, ModerationPrompt, text, 1)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(response) != "0", nil
 }
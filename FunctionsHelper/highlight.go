@@ -0,0 +1,100 @@
+package FunctionsHelper
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Highlight returns a snippet of at most maxLength runes of text around the
+// first occurrence of one of terms, with every occurrence wrapped in
+// <mark></mark>. The text itself is HTML escaped. terms must be lowercase.
+// When no term occurs in text, e.g. because the search engine matched a
+// stemmed form, the start of text is returned.
+func Highlight(text string, terms []string, maxLength int) string {
+	runes := []rune(text)
+	lowered := make([]rune, len(runes))
+	for i, r := range runes {
+		lowered[i] = unicode.ToLower(r)
+	}
+
+	matches := findMatches(lowered, terms)
+	anchor := 0
+	if len(matches) > 0 {
+		anchor = matches[0][0]
+	}
+
+	// Center the snippet window a little before the first match
+	start := 0
+	end := len(runes)
+	if maxLength > 0 && len(runes) > maxLength {
+		start = anchor - maxLength/3
+		if start < 0 {
+			start = 0
+		}
+		end = start + maxLength
+		if end > len(runes) {
+			end = len(runes)
+			start = end - maxLength
+		}
+	}
+
+	var snippet strings.Builder
+	if start > 0 {
+		snippet.WriteString("…")
+	}
+
+	position := start
+	for _, match := range matches {
+		matchStart, matchEnd := match[0], match[1]
+		if matchStart < position || matchEnd > end {
+			continue
+		}
+		snippet.WriteString(html.EscapeString(string(runes[position:matchStart])))
+		snippet.WriteString("<mark>")
+		snippet.WriteString(html.EscapeString(string(runes[matchStart:matchEnd])))
+		snippet.WriteString("</mark>")
+		position = matchEnd
+	}
+	snippet.WriteString(html.EscapeString(string(runes[position:end])))
+
+	if end < len(runes) {
+		snippet.WriteString("…")
+	}
+	return snippet.String()
+}
+
+// findMatches returns the non-overlapping [start, end) rune ranges of terms
+// in lowered, in order of appearance
+func findMatches(lowered []rune, terms []string) [][2]int {
+	var matches [][2]int
+	for i := 0; i < len(lowered); {
+		matched := 0
+		for _, term := range terms {
+			termRunes := []rune(term)
+			if len(termRunes) > matched && hasPrefixAt(lowered, termRunes, i) {
+				matched = len(termRunes)
+			}
+		}
+
+		if matched > 0 {
+			matches = append(matches, [2]int{i, i + matched})
+			i += matched
+		} else {
+			i++
+		}
+	}
+	return matches
+}
+
+func hasPrefixAt(text []rune, prefix []rune, at int) bool {
+	if at+len(prefix) > len(text) {
+		return false
+	}
+	for i, r := range prefix {
+		if text[at+i] != r {
+			return false
+		}
+	}
+	return true
+}
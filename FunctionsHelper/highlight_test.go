@@ -0,0 +1,32 @@
+package FunctionsHelper
+
+import "testing"
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		terms     []string
+		maxLength int
+		want      string
+	}{
+		{"no match", "Hello world", []string{"xyz"}, 0, "Hello world"},
+		{"ignores case", "Go is GREAT", []string{"great"}, 0, "Go is <mark>GREAT</mark>"},
+		{"every occurrence", "go and go", []string{"go"}, 0, "<mark>go</mark> and <mark>go</mark>"},
+		{"longest term wins", "golang", []string{"go", "golang"}, 0, "<mark>golang</mark>"},
+		{"escapes HTML", "<b>go</b>", []string{"go"}, 0, "&lt;b&gt;<mark>go</mark>&lt;/b&gt;"},
+		{"non ASCII", "Čevapčiči je dober", []string{"čevapčiči"}, 0, "<mark>Čevapčiči</mark> je dober"},
+		{"window around the match", "aaaaaaaaaa match bbbbbbbbbb", []string{"match"}, 12, "…aaa <mark>match</mark> bb…"},
+		{"window at the end", "xxxxxxxxxx end", []string{"end"}, 6, "…xx <mark>end</mark>"},
+		{"start without a match", "abcdefgh", []string{"z"}, 4, "abcd…"},
+		{"short text is not cut", "short", []string{"short"}, 100, "<mark>short</mark>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Highlight(tt.text, tt.terms, tt.maxLength); got != tt.want {
+				t.Errorf("Highlight() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package FunctionsHelper
+
+import (
+	"backend/Schemas"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AccessTokenType  = "access"
+	RefreshTokenType = "refresh"
+
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 7 * 24 * time.Hour
+)
+
+// TokenClaims are the claims carried by both access and refresh tokens.
+// The subject is the hex ObjectID of the user and Version the user's token
+// version when the token was issued.
+type TokenClaims struct {
+	Username string `json:"username"`
+	Type     string `json:"typ"`
+	Version  int    `json:"ver"`
+	jwt.RegisteredClaims
+}
+
+// IsRevoked reports whether the token was issued before user's token version
+// was last bumped, e.g. by a password change
+func (claims *TokenClaims) IsRevoked(user Schemas.User) bool {
+	return claims.Version != user.TokenVersion
+}
+
+var (
+	jwtSecret     []byte
+	jwtSecretOnce sync.Once
+)
+
+// SetJWTSecret sets the secret tokens are signed with. It must be called
+// before the first token is issued.
+func SetJWTSecret(secret string) {
+	if secret != "" {
+		jwtSecret = []byte(secret)
+	}
+}
+
+// getJWTSecret returns the configured secret. Without one a random secret is
+// generated, so tokens only stay valid until the process restarts.
+func getJWTSecret() []byte {
+	jwtSecretOnce.Do(func() {
+		if jwtSecret != nil {
+			return
+		}
+
+		slog.Warn("No JWT secret is configured, using a random secret")
+		jwtSecret = make([]byte, 32)
+		if _, err := rand.Read(jwtSecret); err != nil {
+			panic(fmt.Sprintf("could not generate a random JWT secret: %v", err))
+		}
+	})
+	return jwtSecret
+}
+
+// IssueTokenPair signs a new access and refresh token for user.
+func IssueTokenPair(user Schemas.User) (accessToken string, refreshToken string, err error) {
+	accessToken, err = signToken(user, AccessTokenType, AccessTokenTTL)
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshToken, err = signToken(user, RefreshTokenType, RefreshTokenTTL)
+	if err != nil {
+		return "", "", err
+	}
+	return accessToken, refreshToken, nil
+}
+
+func signToken(user Schemas.User, tokenType string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := TokenClaims{
+		Username: user.Name,
+		Type:     tokenType,
+		Version:  user.TokenVersion,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   user.ID.Hex(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(getJWTSecret())
+	if err != nil {
+		return "", fmt.Errorf("failed to sign %s token: %v", tokenType, err)
+	}
+	return signed, nil
+}
+
+// ParseToken validates the signature and expiry of tokenString and checks
+// that it is of the expected type (access or refresh).
+func ParseToken(tokenString string, expectedType string) (*TokenClaims, error) {
+	claims := &TokenClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return getJWTSecret(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Type != expectedType {
+		return nil, errors.New("unexpected token type")
+	}
+	return claims, nil
+}
@@ -0,0 +1,71 @@
+package FunctionsHelper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// OllamaProvider talks to a local Ollama-style /api/chat endpoint.
+type OllamaProvider struct {
+	BaseURL string
+	Model   string
+	Client  *http.Client // Nil gives up after aiRequestTimeout
+}
+
+func (p *OllamaProvider) Name() string {
+	return "ollama"
+}
+
+// Complete sends a non-streaming chat request and returns the message content.
+func (p *OllamaProvider) Complete(ctx context.Context, systemPrompt string, question string, maxTokens int) (string, error) {
+	apiURL := strings.TrimSuffix(p.BaseURL, "/") + "/api/chat"
+
+	requestBody := map[string]interface{}{
+		"model":  p.Model,
+		"stream": false,
+		"messages": []map[string]string{
+			{"role": "system", "content": systemPrompt},
+			{"role": "user", "content": question},
+		},
+		"options": map[string]interface{}{
+			"num_predict": maxTokens,
+		},
+	}
+	jsonBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request body: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClientOrDefault(p.Client).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("API call failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var responseData struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&responseData); err != nil {
+		return "", fmt.Errorf("failed to decode response: %v", err)
+	}
+
+	return responseData.Message.Content, nil
+}
@@ -0,0 +1,27 @@
+package FunctionsHelper
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// NewOneTimeToken returns a random token to mail to a user together with the
+// hash to store. The token itself is never persisted.
+func NewOneTimeToken() (token string, hash string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %v", err)
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(raw)
+	return token, HashOneTimeToken(token), nil
+}
+
+// HashOneTimeToken returns the hex SHA-256 of token, used to look it up
+func HashOneTimeToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,94 @@
+package FunctionsHelper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAIProvider talks to any endpoint implementing the OpenAI chat
+// completions API (OpenAI itself, Azure-style proxies, vLLM, LM Studio, ...).
+type OpenAIProvider struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+	Client  *http.Client // Nil gives up after aiRequestTimeout
+}
+
+func (p *OpenAIProvider) Name() string {
+	return "openai"
+}
+
+// Complete sends a request to the chat completions endpoint and returns the response.
+func (p *OpenAIProvider) Complete(ctx context.Context, systemPrompt string, question string, maxTokens int) (string, error) {
+	apiURL := strings.TrimSuffix(p.BaseURL, "/") + "/chat/completions"
+
+	requestBody := map[string]interface{}{
+		"model": p.Model,
+		"store": true,
+		"messages": []map[string]string{
+			{"role": "system", "content": systemPrompt},
+			{"role": "user", "content": question},
+		},
+		"max_tokens": maxTokens,
+	}
+	jsonBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request body: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if p.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+
+	resp, err := httpClientOrDefault(p.Client).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("API call failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+	var responseData struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&responseData); err != nil {
+		return "", fmt.Errorf("failed to decode response: %v", err)
+	}
+
+	if len(responseData.Choices) == 0 {
+		return "", fmt.Errorf("no choices found in response")
+	}
+
+	return responseData.Choices[0].Message.Content, nil
+}
+
+// aiRequestTimeout bounds a call made with the default client, the caller's
+// context may have no deadline
+const aiRequestTimeout = 30 * time.Second
+
+var defaultHTTPClient = &http.Client{Timeout: aiRequestTimeout}
+
+func httpClientOrDefault(client *http.Client) *http.Client {
+	if client != nil {
+		return client
+	}
+	return defaultHTTPClient
+}
@@ -0,0 +1,54 @@
+package FunctionsHelper
+
+import (
+	"backend/Logging"
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAIProviderFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		provider AIProvider
+	}{
+		{"openai", &OpenAIProvider{BaseURL: server.URL, Model: "test"}},
+		{"ollama", &OllamaProvider{BaseURL: server.URL, Model: "test"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelInfo}))
+			ctx := Logging.WithLogger(context.Background(), logger)
+
+			_, err := instrumentedProvider{tt.provider}.Complete(ctx, "system", "question", 1)
+			if err == nil || !strings.Contains(err.Error(), "503") {
+				t.Fatalf("err = %v, want the status", err)
+			}
+			// The caller reporting the error logs it, not the provider
+			if logs.Len() > 0 {
+				t.Errorf("provider logged the failure:\n%s", logs.String())
+			}
+		})
+	}
+}
+
+func TestDefaultHTTPClientTimesOut(t *testing.T) {
+	if client := httpClientOrDefault(nil); client.Timeout != aiRequestTimeout {
+		t.Fatalf("default client timeout = %v, want %v", client.Timeout, aiRequestTimeout)
+	}
+	custom := &http.Client{}
+	if client := httpClientOrDefault(custom); client != custom {
+		t.Fatal("a configured client was replaced")
+	}
+}
@@ -0,0 +1,27 @@
+package FunctionsHelper
+
+import "errors"
+
+// Shortest password accepted
+const MinPasswordLength = 8
+
+var (
+	ErrPasswordTooShort = errors.New("Password must be at least 8 characters long")
+	ErrPasswordNoNumber = errors.New("Password must contain at least one number")
+)
+
+// ValidatePassword applies the password policy shared by registration and
+// password changes. The errors are meant to be shown to the user.
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return ErrPasswordTooShort
+	}
+
+	// Check if password contains at least one number
+	for _, c := range password {
+		if c >= '0' && c <= '9' {
+			return nil
+		}
+	}
+	return ErrPasswordNoNumber
+}
@@ -1,36 +1,97 @@
 package HTTP
 
 import (
+	"backend/Errors"
 	"backend/Functions"
 
 	"github.com/gin-gonic/gin"
 )
 
 func Router(router *gin.Engine) {
-	router.POST("/register", Functions.Register)
-	router.POST("/login", Functions.Login)
-	router.GET("/profile", Functions.GetProfile)
-	router.POST("/changePassword", Functions.ChangePassword)
+	router.NoRoute(Errors.NoRoute) // Unknown paths get the usual error envelope
 
-	router.GET("/post", Functions.GetPost)
-	router.GET("/posts", Functions.GetAllPosts)
-	router.POST("/post", Functions.CreatePost)
-	router.DELETE("/post", Functions.DeletePost)
-	router.POST("/post/like", Functions.LikePost)
-	router.GET("/post/summarize", Functions.SummarizePost)
+	router.GET("/healthz", Functions.Liveness) // The process is up
+	router.GET("/readyz", Functions.Readiness) // 503 while starting, shutting down or without MongoDB
+	// /metrics is served on its own address, see Metrics.Server
 
-	router.POST("/comment", Functions.CreateComment)
-	router.DELETE("/comment", Functions.DeleteComment)
-	router.POST("/comment/like", Functions.LikeComment)
+	// Each group has its own token bucket, see Functions.RateLimit. Buckets
+	// are per IP unless AuthRequired or OptionalAuth ran first, then per user.
+	authLimit := Functions.RateLimit(Functions.RateLimitAuth)
+	readLimit := Functions.RateLimit(Functions.RateLimitReads)
+	writeLimit := Functions.RateLimit(Functions.RateLimitWrites)
+	aiLimit := Functions.RateLimit(Functions.RateLimitAI)
 
-	router.GET("/lock_old_posts", Functions.LockOldPostsHandler)
+	// Account routes stay per IP whatever token the caller sends
+	account := router.Group("/", readLimit, authLimit)
 
-	router.POST("/create_room", Functions.CreateRoom) // Create a new chatroom
-	router.GET("/rooms", Functions.GetAllRooms)       // List all available chatrooms
-	router.GET("/ws", Functions.HandleConnections)    // WebSocket for joining a specific chatroom
+	account.POST("/register", Functions.Register)
+	account.POST("/login", Functions.Login)
+	account.POST("/token/refresh", Functions.RefreshToken)
+	account.POST("/password/forgot", Functions.ForgotPassword)
+	account.POST("/password/reset", Functions.ResetPassword)
+	account.GET("/verify-email", Functions.VerifyEmail)
 
-	router.POST("/add_tag", Functions.AddTag)
-	router.GET("/tags/names", Functions.GetAllTagNames)
-	router.GET("/tagById", Functions.GetTagNameByID)
+	// Public reads identify callers that send a token, which fills in
+	// liked_by_me and limits them per user
+	public := router.Group("/", Functions.OptionalAuth, readLimit)
 
+	public.GET("/post", Functions.GetPost)
+	public.GET("/posts", Functions.GetAllPosts)
+	public.GET("/post/summarize", aiLimit, Functions.SummarizePost)
+	public.GET("/search", Functions.SearchPosts)
+
+	public.GET("/rooms", Functions.GetAllRooms)                    // List all available chatrooms
+	public.GET("/rooms/:name/messages", Functions.GetRoomMessages) // Paged history of a chatroom
+	public.GET("/ws", Functions.HandleConnections)                 // WebSocket for joining a specific chatroom, sending needs a token and is limited as "chat"
+
+	public.GET("/tags/names", Functions.GetAllTagNames)
+	public.GET("/tagById", Functions.GetTagNameByID)
+
+	// Routes below require a valid access token
+	authorized := router.Group("/")
+	authorized.Use(Functions.AuthRequired)
+
+	authorized.GET("/profile", readLimit, Functions.GetProfile)
+	authorized.POST("/changePassword", authLimit, Functions.ChangePassword)
+	authorized.POST("/verify-email/resend", authLimit, Functions.ResendVerificationEmail)
+
+	// Everything else changes data and shares the per user write limit
+	writes := authorized.Group("/", writeLimit)
+
+	// Creating content needs a verified email address and is checked by AI
+	writes.POST("/post", Functions.RequireVerified, aiLimit, Functions.CreatePost)
+	writes.DELETE("/post", Functions.DeletePost)
+	writes.POST("/post/like", Functions.LikePost)
+	writes.DELETE("/post/like", Functions.UnlikePost)
+
+	writes.POST("/comment", Functions.RequireVerified, aiLimit, Functions.CreateComment)
+	writes.DELETE("/comment", Functions.DeleteComment)
+	writes.POST("/comment/like", Functions.LikeComment)
+	writes.DELETE("/comment/like", Functions.UnlikeComment)
+
+	writes.POST("/create_room", Functions.RequireVerified, Functions.CreateRoom) // Create a new chatroom
+
+	writes.POST("/add_tag", Functions.RequirePermission(Functions.PermissionCreateTag), Functions.AddTag)
+
+	// Moderation and administration, each route checks the permission matrix
+	admin := writes.Group("/admin")
+
+	admin.POST("/posts/:id/lock", Functions.RequirePermission(Functions.PermissionLockPost), Functions.LockPost)
+	admin.DELETE("/posts/:id/lock", Functions.RequirePermission(Functions.PermissionLockPost), Functions.UnlockPost)
+	admin.POST("/posts/:id/pin", Functions.RequirePermission(Functions.PermissionLockPost), Functions.PinPost)
+	admin.DELETE("/posts/:id/pin", Functions.RequirePermission(Functions.PermissionLockPost), Functions.UnpinPost)
+
+	admin.POST("/users/:username/ban", Functions.RequirePermission(Functions.PermissionBanUser), Functions.BanUser)
+	admin.DELETE("/users/:username/ban", Functions.RequirePermission(Functions.PermissionBanUser), Functions.UnbanUser)
+	admin.PUT("/users/:username/role", Functions.RequirePermission(Functions.PermissionManageUsers), Functions.SetUserRole)
+
+	admin.DELETE("/rooms/:name", Functions.RequirePermission(Functions.PermissionManageRooms), Functions.DeleteRoom)
+
+	admin.POST("/jobs/:name/run", Functions.RequirePermission(Functions.PermissionManageJobs), Functions.RunJob) // Start a job right away
+
+	// Administrative reads change nothing and share the read limit instead
+	adminReads := authorized.Group("/admin", readLimit)
+
+	adminReads.GET("/jobs", Functions.RequirePermission(Functions.PermissionManageJobs), Functions.GetJobs)                               // Scheduled jobs and their last run
+	adminReads.GET("/lock_old_posts/preview", Functions.RequirePermission(Functions.PermissionManageJobs), Functions.PreviewLockOldPosts) // Posts the lock job would lock now
 }
@@ -0,0 +1,78 @@
+package HTTP
+
+import (
+	"backend/Config"
+	"backend/Errors"
+	"backend/Functions"
+	"backend/FunctionsHelper"
+	"backend/Repository"
+	"backend/Schemas"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRouterRateLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	FunctionsHelper.SetJWTSecret("test-secret")
+	Repository.Use(Repository.NewMemoryRepositories())
+
+	config := Config.Default()
+	config.RateLimit.Backend = "memory"
+	config.RateLimit.Limits["reads"] = "2/1m"
+	config.RateLimit.Limits["writes"] = "1/1m"
+	Functions.Configure(config)
+
+	tokens := map[string]string{"": ""}
+	for name, role := range map[string]string{"alice": Schemas.RoleUser, "bob": Schemas.RoleUser, "admin": Schemas.RoleAdmin} {
+		user := Schemas.User{Name: name, Email: name + "@example.com", Role: role}
+		id, err := Repository.Users().Create(context.Background(), user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		user.ID = id
+		if tokens[name], _, err = FunctionsHelper.IssueTokenPair(user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	router := gin.New()
+	router.ContextWithFallback = true
+	router.Use(gin.CustomRecovery(Errors.Recovery), Errors.Middleware)
+	Router(router)
+
+	// Every request comes from the same IP, so only per user buckets tell
+	// the users apart
+	tests := []struct {
+		name       string
+		path       string
+		user       string
+		wantStatus int
+	}{
+		{"first read", "/posts", "alice", http.StatusOK},
+		{"second read", "/posts", "alice", http.StatusOK},
+		{"read limit reached", "/posts", "alice", http.StatusTooManyRequests},
+		{"other user has own bucket", "/posts", "bob", http.StatusOK},
+		{"anonymous has the IP bucket", "/posts", "", http.StatusOK},
+		{"admin read", "/admin/jobs", "admin", http.StatusOK},
+		{"admin read past the write limit", "/admin/jobs", "admin", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if token := tokens[tt.user]; token != "" {
+				request.Header.Set("Authorization", "Bearer "+token)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+		})
+	}
+}
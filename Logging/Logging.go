@@ -0,0 +1,117 @@
+// Package Logging sets up the structured logger and carries a logger per
+// request through contexts, so every line a request writes has its
+// request_id, route and user.
+package Logging
+
+import (
+	"backend/Config"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the request ID in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// Longest request ID accepted from a client
+const maxRequestIDLength = 128
+
+// New returns a logger writing to w in cfg's format (json or text) from
+// cfg's level on
+func New(cfg Config.Logging, w io.Writer) *slog.Logger {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		level = slog.LevelInfo
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	if cfg.Format == "text" {
+		return slog.New(slog.NewTextHandler(w, options))
+	}
+	return slog.New(slog.NewJSONHandler(w, options))
+}
+
+type loggerKey struct{}
+
+// WithLogger returns a copy of ctx carrying logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger of the request ctx belongs to, or the
+// default logger outside of requests
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// With adds attributes to every later line of the request, e.g. the user
+// once AuthRequired knows it
+func With(c *gin.Context, args ...any) {
+	ctx := c.Request.Context()
+	c.Request = c.Request.WithContext(WithLogger(ctx, FromContext(ctx).With(args...)))
+}
+
+// Middleware assigns the request ID, taking a sane one from the client,
+// echoes it in the response, attaches the request logger and logs the
+// request once it is done
+func Middleware(c *gin.Context) {
+	start := time.Now()
+
+	requestID := c.GetHeader(RequestIDHeader)
+	if !validRequestID(requestID) {
+		requestID = newRequestID()
+	}
+	c.Header(RequestIDHeader, requestID)
+
+	logger := slog.Default().With("request_id", requestID, "method", c.Request.Method, "route", route(c))
+	c.Request = c.Request.WithContext(WithLogger(c.Request.Context(), logger))
+
+	c.Next()
+
+	level := slog.LevelInfo
+	if c.Writer.Status() >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	FromContext(c.Request.Context()).Log(c.Request.Context(), level, "request",
+		"path", c.Request.URL.Path,
+		"status", c.Writer.Status(),
+		"latency_ms", time.Since(start).Milliseconds(),
+		"client_ip", c.ClientIP(),
+		"bytes", c.Writer.Size(),
+	)
+}
+
+// route is the route template, so lines of one endpoint group together
+func route(c *gin.Context) string {
+	if route := c.FullPath(); route != "" {
+		return route
+	}
+	return "unmatched"
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	return strings.IndexFunc(id, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.')
+	}) < 0
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(id)
+}
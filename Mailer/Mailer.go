@@ -0,0 +1,70 @@
+// Package Mailer sends the emails of the application (password resets,
+// address verification). The configured backend is smtp for real delivery,
+// file to write each email to a directory, or log (the default) to print
+// them, which is enough for local development.
+package Mailer
+
+import (
+	"backend/Config"
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+var (
+	current   Mailer
+	currentMu sync.Mutex
+)
+
+// Get returns the configured mailer, the default configuration's when main
+// has not set one
+func Get() Mailer {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+
+	if current == nil {
+		current = New(Config.Default().Mail)
+	}
+	return current
+}
+
+// Set replaces the mailer, e.g. with a FileMailer in tests
+func Set(mailer Mailer) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+
+	current = mailer
+}
+
+// New creates the mailer for cfg's backend
+func New(cfg Config.Mail) Mailer {
+	switch cfg.Backend {
+	case "smtp":
+		return &SMTPMailer{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.From,
+		}
+	case "file":
+		return &FileMailer{Dir: cfg.Dir, From: cfg.From}
+	case "log":
+	default:
+		slog.Warn("Unknown mail backend, falling back to log", "mailer", cfg.Backend)
+	}
+
+	return &LogMailer{From: cfg.From}
+}
@@ -0,0 +1,57 @@
+package Mailer
+
+import (
+	"backend/Logging"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogMailer prints messages to the log instead of sending them
+type LogMailer struct {
+	From string
+}
+
+func (m *LogMailer) Send(ctx context.Context, message Message) error {
+	Logging.FromContext(ctx).Info("Email not sent, logging it instead", "from", m.From, "to", message.To, "subject", message.Subject, "body", message.Body)
+	return nil
+}
+
+// FileMailer writes every message to its own .eml file in Dir and remembers
+// them, so tests can read back what would have been sent
+type FileMailer struct {
+	Dir  string
+	From string
+
+	mu   sync.Mutex
+	sent []Message
+}
+
+func (m *FileMailer) Send(ctx context.Context, message Message) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return fmt.Errorf("(FileMailer.Send) %v", err)
+	}
+
+	recipient := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(message.To)
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), recipient)
+	if err := os.WriteFile(filepath.Join(m.Dir, name), format(m.From, message), 0o644); err != nil {
+		return fmt.Errorf("(FileMailer.Send) %v", err)
+	}
+
+	m.mu.Lock()
+	m.sent = append(m.sent, message)
+	m.mu.Unlock()
+	return nil
+}
+
+// Sent returns the messages sent so far, oldest first
+func (m *FileMailer) Sent() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Message(nil), m.sent...)
+}
@@ -0,0 +1,56 @@
+package Mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPMailer delivers messages through an SMTP server, authenticating with
+// PLAIN when a username is set
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, message Message) error {
+	// net/smtp has no context support, give up when the context would have
+	done := make(chan error, 1)
+	go func() {
+		var auth smtp.Auth
+		if m.Username != "" {
+			auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+		}
+		done <- smtp.SendMail(net.JoinHostPort(m.Host, m.Port), auth, m.From, []string{message.To}, format(m.From, message))
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("(SMTPMailer.Send) %v", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// format renders message as an RFC 5322 email
+func format(from string, message Message) []byte {
+	var email strings.Builder
+	fmt.Fprintf(&email, "From: %s\r\n", from)
+	fmt.Fprintf(&email, "To: %s\r\n", message.To)
+	fmt.Fprintf(&email, "Subject: %s\r\n", message.Subject)
+	fmt.Fprintf(&email, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	email.WriteString("MIME-Version: 1.0\r\n")
+	email.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	email.WriteString("\r\n")
+	email.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	return []byte(email.String())
+}
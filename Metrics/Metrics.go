@@ -0,0 +1,102 @@
+// Package Metrics defines the Prometheus metrics of the backend. They are
+// registered with the default registry and served by Handler on /metrics of
+// Server, a listener apart from the API.
+package Metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// HTTPRequestDuration is the latency of every request by route template
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of HTTP requests.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// AICalls counts calls to the AI provider by outcome (success or error)
+	AICalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ai_calls_total",
+		Help: "Calls to the AI provider.",
+	}, []string{"provider", "outcome"})
+
+	// AICallDuration is the latency of calls to the AI provider
+	AICallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ai_call_duration_seconds",
+		Help:    "Latency of calls to the AI provider.",
+		Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"provider"})
+
+	// ChatConnections is the number of open WebSocket connections per room
+	ChatConnections = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "chat_connections",
+		Help: "Open chat WebSocket connections.",
+	}, []string{"room"})
+
+	// JobRuns counts finished job runs by outcome (success or failure)
+	JobRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "job_runs_total",
+		Help: "Finished runs of background jobs.",
+	}, []string{"job", "trigger", "outcome"})
+
+	// JobRunDuration is how long job runs take
+	JobRunDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "job_run_duration_seconds",
+		Help:    "Duration of background job runs.",
+		Buckets: []float64{0.1, 1, 5, 15, 60, 300},
+	}, []string{"job"})
+)
+
+// Outcome labels
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+// Outcome returns the outcome label for err
+func Outcome(err error) string {
+	if err != nil {
+		return OutcomeError
+	}
+	return OutcomeSuccess
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Server returns the server for /metrics on addr. It is kept off the public
+// API, addr should only be reachable by the scraper.
+func Server(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	return &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
+
+// Middleware records HTTPRequestDuration. Requests matching no route share
+// one label so scanners cannot create a series per path.
+func Middleware(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	HTTPRequestDuration.
+		WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+		Observe(time.Since(start).Seconds())
+}
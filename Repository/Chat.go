@@ -0,0 +1,303 @@
+package Repository
+
+import (
+	"backend/Schemas"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RoomRepository stores chat rooms, identified by their unique name
+type RoomRepository interface {
+	FindAll(ctx context.Context) ([]Schemas.ChatRoom, error)
+	FindByName(ctx context.Context, name string) (Schemas.ChatRoom, error)
+	// Create returns ErrDuplicate when a room with the same name exists
+	Create(ctx context.Context, room Schemas.ChatRoom) (primitive.ObjectID, error)
+	// Delete reports whether a room was deleted
+	Delete(ctx context.Context, name string) (bool, error)
+}
+
+// ErrNoSender is returned when a chat message without an authenticated
+// sender is stored
+var ErrNoSender = errors.New("chat message has no sender")
+
+// MessageRepository stores the history of chat rooms. Only messages of
+// authenticated senders are kept, so history cannot impersonate anyone.
+type MessageRepository interface {
+	// Create returns ErrNoSender when message has no UserID
+	Create(ctx context.Context, message Schemas.ChatMessage) (primitive.ObjectID, error)
+	// FindBefore returns up to limit of the newest messages of room older
+	// than before (or the newest overall when before is zero), oldest first.
+	// Messages stored before senders were recorded are left out.
+	FindBefore(ctx context.Context, room string, before primitive.ObjectID, limit int) ([]Schemas.ChatMessage, error)
+	// DeleteByRoom deletes the whole history of room and returns how many
+	// messages it had
+	DeleteByRoom(ctx context.Context, room string) (int64, error)
+}
+
+// ─── MongoDB ────────────────────────────────────────────────────────────────
+
+type mongoRoomRepository struct{}
+
+func (r *mongoRoomRepository) FindAll(ctx context.Context) ([]Schemas.ChatRoom, error) {
+	coll, err := collection(RoomsCollection)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := coll.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	rooms := make([]Schemas.ChatRoom, 0)
+	if err := cursor.All(ctx, &rooms); err != nil {
+		return nil, err
+	}
+	return rooms, nil
+}
+
+func (r *mongoRoomRepository) FindByName(ctx context.Context, name string) (Schemas.ChatRoom, error) {
+	var room Schemas.ChatRoom
+	coll, err := collection(RoomsCollection)
+	if err != nil {
+		return Schemas.ChatRoom{}, err
+	}
+	err = coll.FindOne(ctx, bson.M{"name": name}).Decode(&room)
+	return room, notFound(err)
+}
+
+func (r *mongoRoomRepository) Create(ctx context.Context, room Schemas.ChatRoom) (primitive.ObjectID, error) {
+	coll, err := collection(RoomsCollection)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	result, err := coll.InsertOne(ctx, room)
+	if mongo.IsDuplicateKeyError(err) {
+		return primitive.NilObjectID, ErrDuplicate
+	}
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	id, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return primitive.NilObjectID, errors.New("inserted room ID is not an ObjectID")
+	}
+	return id, nil
+}
+
+func (r *mongoRoomRepository) Delete(ctx context.Context, name string) (bool, error) {
+	coll, err := collection(RoomsCollection)
+	if err != nil {
+		return false, err
+	}
+	result, err := coll.DeleteOne(ctx, bson.M{"name": name})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+func (r *mongoRoomRepository) ensureIndexes(ctx context.Context) error {
+	coll, err := collection(RoomsCollection)
+	if err != nil {
+		return err
+	}
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+type mongoMessageRepository struct{}
+
+func (r *mongoMessageRepository) Create(ctx context.Context, message Schemas.ChatMessage) (primitive.ObjectID, error) {
+	if message.UserID.IsZero() {
+		return primitive.NilObjectID, ErrNoSender
+	}
+
+	coll, err := collection(MessagesCollection)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	result, err := coll.InsertOne(ctx, message)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	id, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return primitive.NilObjectID, errors.New("inserted message ID is not an ObjectID")
+	}
+	return id, nil
+}
+
+func (r *mongoMessageRepository) FindBefore(ctx context.Context, room string, before primitive.ObjectID, limit int) ([]Schemas.ChatMessage, error) {
+	filter := bson.M{"room": room, "user_id": bson.M{"$exists": true}}
+	if !before.IsZero() {
+		filter["_id"] = bson.M{"$lt": before}
+	}
+
+	findOptions := options.Find().SetSort(bson.M{"_id": -1}).SetLimit(int64(normalizeLimit(limit)))
+	coll, err := collection(MessagesCollection)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := coll.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]Schemas.ChatMessage, 0)
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+	reverseMessages(messages)
+	return messages, nil
+}
+
+func (r *mongoMessageRepository) DeleteByRoom(ctx context.Context, room string) (int64, error) {
+	coll, err := collection(MessagesCollection)
+	if err != nil {
+		return 0, err
+	}
+	result, err := coll.DeleteMany(ctx, bson.M{"room": room})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (r *mongoMessageRepository) ensureIndexes(ctx context.Context) error {
+	coll, err := collection(MessagesCollection)
+	if err != nil {
+		return err
+	}
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "room", Value: 1}, {Key: "_id", Value: -1}},
+	})
+	return err
+}
+
+func reverseMessages(messages []Schemas.ChatMessage) {
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+}
+
+// ─── In memory ──────────────────────────────────────────────────────────────
+
+type memoryRoomRepository struct {
+	mu    sync.RWMutex
+	rooms map[string]Schemas.ChatRoom
+}
+
+func newMemoryRoomRepository() *memoryRoomRepository {
+	return &memoryRoomRepository{rooms: make(map[string]Schemas.ChatRoom)}
+}
+
+func (r *memoryRoomRepository) FindAll(ctx context.Context) ([]Schemas.ChatRoom, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rooms := make([]Schemas.ChatRoom, 0, len(r.rooms))
+	for _, room := range r.rooms {
+		rooms = append(rooms, room)
+	}
+	sort.Slice(rooms, func(i, j int) bool { return rooms[i].Name < rooms[j].Name })
+	return rooms, nil
+}
+
+func (r *memoryRoomRepository) FindByName(ctx context.Context, name string) (Schemas.ChatRoom, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	room, ok := r.rooms[name]
+	if !ok {
+		return Schemas.ChatRoom{}, ErrNotFound
+	}
+	return room, nil
+}
+
+func (r *memoryRoomRepository) Create(ctx context.Context, room Schemas.ChatRoom) (primitive.ObjectID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.rooms[room.Name]; exists {
+		return primitive.NilObjectID, ErrDuplicate
+	}
+	if room.ID.IsZero() {
+		room.ID = primitive.NewObjectID()
+	}
+	r.rooms[room.Name] = room
+	return room.ID, nil
+}
+
+func (r *memoryRoomRepository) Delete(ctx context.Context, name string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.rooms[name]; !exists {
+		return false, nil
+	}
+	delete(r.rooms, name)
+	return true, nil
+}
+
+type memoryMessageRepository struct {
+	mu       sync.RWMutex
+	messages map[string][]Schemas.ChatMessage
+}
+
+func newMemoryMessageRepository() *memoryMessageRepository {
+	return &memoryMessageRepository{messages: make(map[string][]Schemas.ChatMessage)}
+}
+
+func (r *memoryMessageRepository) Create(ctx context.Context, message Schemas.ChatMessage) (primitive.ObjectID, error) {
+	if message.UserID.IsZero() {
+		return primitive.NilObjectID, ErrNoSender
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if message.ID.IsZero() {
+		message.ID = primitive.NewObjectID()
+	}
+	r.messages[message.Room] = append(r.messages[message.Room], message)
+	return message.ID, nil
+}
+
+func (r *memoryMessageRepository) FindBefore(ctx context.Context, room string, before primitive.ObjectID, limit int) ([]Schemas.ChatMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	limit = normalizeLimit(limit)
+	history := r.messages[room]
+
+	// Messages are appended in insertion order, walk back from the newest
+	messages := make([]Schemas.ChatMessage, 0, limit)
+	for i := len(history) - 1; i >= 0 && len(messages) < limit; i-- {
+		if !history[i].UserID.IsZero() && (before.IsZero() || history[i].ID.Hex() < before.Hex()) {
+			messages = append(messages, history[i])
+		}
+	}
+	reverseMessages(messages)
+	return messages, nil
+}
+
+func (r *memoryMessageRepository) DeleteByRoom(ctx context.Context, room string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted := int64(len(r.messages[room]))
+	delete(r.messages, room)
+	return deleted, nil
+}
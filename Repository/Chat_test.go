@@ -0,0 +1,40 @@
+package Repository
+
+import (
+	"backend/Schemas"
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryMessagesWithoutSender(t *testing.T) {
+	ctx := context.Background()
+	messages := newMemoryMessageRepository()
+
+	if _, err := messages.Create(ctx, Schemas.ChatMessage{Room: "general", Content: "anonymous"}); !errors.Is(err, ErrNoSender) {
+		t.Fatalf("err = %v, want ErrNoSender", err)
+	}
+
+	sender := primitive.NewObjectID()
+	for _, content := range []string{"first", "second"} {
+		if _, err := messages.Create(ctx, Schemas.ChatMessage{Room: "general", UserID: sender, Content: content}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A message stored before senders were recorded, newer than the others
+	messages.messages["general"] = append(messages.messages["general"], Schemas.ChatMessage{
+		ID: primitive.NewObjectID(), Room: "general", Content: "legacy",
+	})
+
+	found, err := messages.FindBefore(ctx, "general", primitive.NilObjectID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var contents []string
+	for _, message := range found {
+		contents = append(contents, message.Content)
+	}
+	assertOrder(t, "messages", contents, []string{"first", "second"})
+}
@@ -0,0 +1,324 @@
+package Repository
+
+import (
+	"backend/Schemas"
+	"context"
+	"errors"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CommentRepository stores comments, which reference posts by hex post ID
+type CommentRepository interface {
+	FindByID(ctx context.Context, id primitive.ObjectID) (Schemas.Comment, error)
+	FindByPostID(ctx context.Context, postID string) ([]Schemas.Comment, error)
+	// FindByPostIDs loads the comments of many posts at once, keyed by post ID
+	FindByPostIDs(ctx context.Context, postIDs []string) (map[string][]Schemas.Comment, error)
+	// CountByPostIDs counts the comments of many posts at once, keyed by post ID
+	CountByPostIDs(ctx context.Context, postIDs []string) (map[string]int, error)
+	Create(ctx context.Context, comment Schemas.Comment) (primitive.ObjectID, error)
+	// Delete reports whether a comment was deleted
+	Delete(ctx context.Context, id primitive.ObjectID) (bool, error)
+	// DeleteByPostID deletes every comment of a post and returns how many
+	DeleteByPostID(ctx context.Context, postID string) (int64, error)
+	// IncrementLikes reports whether a comment matched id
+	IncrementLikes(ctx context.Context, id primitive.ObjectID, delta int) (bool, error)
+}
+
+// ─── MongoDB ────────────────────────────────────────────────────────────────
+
+type mongoCommentRepository struct{}
+
+func (r *mongoCommentRepository) FindByID(ctx context.Context, id primitive.ObjectID) (Schemas.Comment, error) {
+	var comment Schemas.Comment
+	coll, err := collection(CommentsCollection)
+	if err != nil {
+		return Schemas.Comment{}, err
+	}
+	err = coll.FindOne(ctx, bson.M{"_id": id}).Decode(&comment)
+	return comment, notFound(err)
+}
+
+func (r *mongoCommentRepository) FindByPostID(ctx context.Context, postID string) ([]Schemas.Comment, error) {
+	coll, err := collection(CommentsCollection)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := coll.Find(ctx, bson.M{"post_id": postID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	comments := make([]Schemas.Comment, 0)
+	for cursor.Next(ctx) {
+		var comment Schemas.Comment
+		// Skip comments that cannot be decoded instead of failing the whole post
+		if err := cursor.Decode(&comment); err == nil {
+			comments = append(comments, comment)
+		}
+	}
+	return comments, cursor.Err()
+}
+
+func (r *mongoCommentRepository) FindByPostIDs(ctx context.Context, postIDs []string) (map[string][]Schemas.Comment, error) {
+	commentsByPost := make(map[string][]Schemas.Comment, len(postIDs))
+	for _, postID := range postIDs {
+		commentsByPost[postID] = make([]Schemas.Comment, 0)
+	}
+
+	coll, err := collection(CommentsCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	// Query in batches so a huge $in list never exceeds the document size limit
+	for _, batch := range batches(postIDs, maxInBatch) {
+		cursor, err := coll.Find(ctx, bson.M{"post_id": bson.M{"$in": batch}})
+		if err != nil {
+			return nil, err
+		}
+
+		for cursor.Next(ctx) {
+			var comment Schemas.Comment
+			if err := cursor.Decode(&comment); err == nil {
+				commentsByPost[comment.PostId] = append(commentsByPost[comment.PostId], comment)
+			}
+		}
+		err = cursor.Err()
+		cursor.Close(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return commentsByPost, nil
+}
+
+func (r *mongoCommentRepository) CountByPostIDs(ctx context.Context, postIDs []string) (map[string]int, error) {
+	counts := make(map[string]int, len(postIDs))
+	for _, postID := range postIDs {
+		counts[postID] = 0
+	}
+
+	coll, err := collection(CommentsCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, batch := range batches(postIDs, maxInBatch) {
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"post_id": bson.M{"$in": batch}}}},
+			{{Key: "$group", Value: bson.M{"_id": "$post_id", "count": bson.M{"$sum": 1}}}},
+		}
+		cursor, err := coll.Aggregate(ctx, pipeline)
+		if err != nil {
+			return nil, err
+		}
+
+		var results []struct {
+			PostID string `bson:"_id"`
+			Count  int    `bson:"count"`
+		}
+		if err := cursor.All(ctx, &results); err != nil {
+			return nil, err
+		}
+		for _, result := range results {
+			counts[result.PostID] = result.Count
+		}
+	}
+	return counts, nil
+}
+
+func (r *mongoCommentRepository) Create(ctx context.Context, comment Schemas.Comment) (primitive.ObjectID, error) {
+	coll, err := collection(CommentsCollection)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	result, err := coll.InsertOne(ctx, comment)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	id, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return primitive.NilObjectID, errors.New("inserted comment ID is not an ObjectID")
+	}
+	return id, nil
+}
+
+func (r *mongoCommentRepository) Delete(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	coll, err := collection(CommentsCollection)
+	if err != nil {
+		return false, err
+	}
+	result, err := coll.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+func (r *mongoCommentRepository) DeleteByPostID(ctx context.Context, postID string) (int64, error) {
+	coll, err := collection(CommentsCollection)
+	if err != nil {
+		return 0, err
+	}
+	result, err := coll.DeleteMany(ctx, bson.M{"post_id": postID})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (r *mongoCommentRepository) IncrementLikes(ctx context.Context, id primitive.ObjectID, delta int) (bool, error) {
+	coll, err := collection(CommentsCollection)
+	if err != nil {
+		return false, err
+	}
+	result, err := coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"likeCount": delta}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// ensureIndexes indexes post_id, which every lookup by post filters on
+func (r *mongoCommentRepository) ensureIndexes(ctx context.Context) error {
+	coll, err := collection(CommentsCollection)
+	if err != nil {
+		return err
+	}
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "post_id", Value: 1}},
+	})
+	return err
+}
+
+// ─── In memory ──────────────────────────────────────────────────────────────
+
+type memoryCommentRepository struct {
+	mu       sync.RWMutex
+	comments map[primitive.ObjectID]Schemas.Comment
+	order    []primitive.ObjectID
+}
+
+func newMemoryCommentRepository() *memoryCommentRepository {
+	return &memoryCommentRepository{comments: make(map[primitive.ObjectID]Schemas.Comment)}
+}
+
+func (r *memoryCommentRepository) FindByID(ctx context.Context, id primitive.ObjectID) (Schemas.Comment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	comment, ok := r.comments[id]
+	if !ok {
+		return Schemas.Comment{}, ErrNotFound
+	}
+	return comment, nil
+}
+
+func (r *memoryCommentRepository) FindByPostID(ctx context.Context, postID string) ([]Schemas.Comment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	comments := make([]Schemas.Comment, 0)
+	for _, id := range r.order {
+		if comment, ok := r.comments[id]; ok && comment.PostId == postID {
+			comments = append(comments, comment)
+		}
+	}
+	return comments, nil
+}
+
+func (r *memoryCommentRepository) FindByPostIDs(ctx context.Context, postIDs []string) (map[string][]Schemas.Comment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	commentsByPost := make(map[string][]Schemas.Comment, len(postIDs))
+	for _, postID := range postIDs {
+		commentsByPost[postID] = make([]Schemas.Comment, 0)
+	}
+	for _, id := range r.order {
+		comment := r.comments[id]
+		if comments, ok := commentsByPost[comment.PostId]; ok {
+			commentsByPost[comment.PostId] = append(comments, comment)
+		}
+	}
+	return commentsByPost, nil
+}
+
+func (r *memoryCommentRepository) CountByPostIDs(ctx context.Context, postIDs []string) (map[string]int, error) {
+	commentsByPost, err := r.FindByPostIDs(ctx, postIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(commentsByPost))
+	for postID, comments := range commentsByPost {
+		counts[postID] = len(comments)
+	}
+	return counts, nil
+}
+
+func (r *memoryCommentRepository) Create(ctx context.Context, comment Schemas.Comment) (primitive.ObjectID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if comment.ID.IsZero() {
+		comment.ID = primitive.NewObjectID()
+	}
+	r.comments[comment.ID] = comment
+	r.order = append(r.order, comment.ID)
+	return comment.ID, nil
+}
+
+func (r *memoryCommentRepository) Delete(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.comments[id]; !ok {
+		return false, nil
+	}
+	delete(r.comments, id)
+	for i, orderedID := range r.order {
+		if orderedID == id {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	return true, nil
+}
+
+func (r *memoryCommentRepository) DeleteByPostID(ctx context.Context, postID string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	remaining := r.order[:0]
+	for _, id := range r.order {
+		if r.comments[id].PostId == postID {
+			delete(r.comments, id)
+			deleted++
+			continue
+		}
+		remaining = append(remaining, id)
+	}
+	r.order = remaining
+	return deleted, nil
+}
+
+func (r *memoryCommentRepository) IncrementLikes(ctx context.Context, id primitive.ObjectID, delta int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comment, ok := r.comments[id]
+	if !ok {
+		return false, nil
+	}
+	comment.LikeCount += delta
+	r.comments[id] = comment
+	return true, nil
+}
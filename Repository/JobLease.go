@@ -0,0 +1,93 @@
+package Repository
+
+import (
+	"backend/Schemas"
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// JobLeaseRepository hands out one lease per job. A lease that expired, e.g.
+// because its holder died, can be taken over.
+type JobLeaseRepository interface {
+	// Acquire takes the lease of job for holder until expiresAt and reports
+	// whether it got it
+	Acquire(ctx context.Context, job string, holder string, now time.Time, expiresAt time.Time) (bool, error)
+	// Release gives the lease up, provided holder still has it
+	Release(ctx context.Context, job string, holder string) error
+}
+
+// ─── MongoDB ────────────────────────────────────────────────────────────────
+
+type mongoJobLeaseRepository struct{}
+
+func (r *mongoJobLeaseRepository) Acquire(ctx context.Context, job string, holder string, now time.Time, expiresAt time.Time) (bool, error) {
+	// Matches a free or expired lease, or creates the first one. A lease held
+	// by someone else does not match, so the upsert clashes on _id.
+	filter := bson.M{"_id": job, "expires_at": bson.M{"$lte": now}}
+	update := bson.M{"$set": bson.M{"holder": holder, "expires_at": expiresAt}}
+
+	coll, err := collection(JobLeasesCollection)
+	if err != nil {
+		return false, err
+	}
+	err = coll.
+		FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetUpsert(true)).
+		Err()
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil && err != mongo.ErrNoDocuments {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *mongoJobLeaseRepository) Release(ctx context.Context, job string, holder string) error {
+	// Expire rather than delete, the document stays for the next Acquire
+	coll, err := collection(JobLeasesCollection)
+	if err != nil {
+		return err
+	}
+	_, err = coll.UpdateOne(ctx,
+		bson.M{"_id": job, "holder": holder},
+		bson.M{"$set": bson.M{"expires_at": time.Time{}}},
+	)
+	return err
+}
+
+// ─── In memory ──────────────────────────────────────────────────────────────
+
+type memoryJobLeaseRepository struct {
+	mu     sync.Mutex
+	leases map[string]Schemas.JobLease
+}
+
+func newMemoryJobLeaseRepository() *memoryJobLeaseRepository {
+	return &memoryJobLeaseRepository{leases: make(map[string]Schemas.JobLease)}
+}
+
+func (r *memoryJobLeaseRepository) Acquire(ctx context.Context, job string, holder string, now time.Time, expiresAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if lease, ok := r.leases[job]; ok && lease.ExpiresAt.After(now) {
+		return false, nil
+	}
+	r.leases[job] = Schemas.JobLease{Job: job, Holder: holder, ExpiresAt: expiresAt}
+	return true, nil
+}
+
+func (r *memoryJobLeaseRepository) Release(ctx context.Context, job string, holder string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if lease, ok := r.leases[job]; ok && lease.Holder == holder {
+		delete(r.leases, job)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package Repository
+
+import (
+	"backend/Schemas"
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// JobRunRepository records the outcome of scheduled job runs
+type JobRunRepository interface {
+	Record(ctx context.Context, run Schemas.JobRun) error
+	// FindLatest returns the most recent run of job
+	FindLatest(ctx context.Context, job string) (Schemas.JobRun, error)
+}
+
+// ─── MongoDB ────────────────────────────────────────────────────────────────
+
+type mongoJobRunRepository struct{}
+
+func (r *mongoJobRunRepository) Record(ctx context.Context, run Schemas.JobRun) error {
+	coll, err := collection(JobRunsCollection)
+	if err != nil {
+		return err
+	}
+	_, err = coll.InsertOne(ctx, run)
+	return err
+}
+
+func (r *mongoJobRunRepository) FindLatest(ctx context.Context, job string) (Schemas.JobRun, error) {
+	var run Schemas.JobRun
+	findOptions := options.FindOne().SetSort(bson.M{"started_at": -1})
+	coll, err := collection(JobRunsCollection)
+	if err != nil {
+		return Schemas.JobRun{}, err
+	}
+	err = coll.FindOne(ctx, bson.M{"job": job}, findOptions).Decode(&run)
+	return run, notFound(err)
+}
+
+func (r *mongoJobRunRepository) ensureIndexes(ctx context.Context) error {
+	coll, err := collection(JobRunsCollection)
+	if err != nil {
+		return err
+	}
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "job", Value: 1}, {Key: "started_at", Value: -1}},
+	})
+	return err
+}
+
+// ─── In memory ──────────────────────────────────────────────────────────────
+
+type memoryJobRunRepository struct {
+	mu     sync.RWMutex
+	latest map[string]Schemas.JobRun
+}
+
+func newMemoryJobRunRepository() *memoryJobRunRepository {
+	return &memoryJobRunRepository{latest: make(map[string]Schemas.JobRun)}
+}
+
+func (r *memoryJobRunRepository) Record(ctx context.Context, run Schemas.JobRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if previous, ok := r.latest[run.Job]; !ok || !run.StartedAt.Before(previous.StartedAt) {
+		r.latest[run.Job] = run
+	}
+	return nil
+}
+
+func (r *memoryJobRunRepository) FindLatest(ctx context.Context, job string) (Schemas.JobRun, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	run, ok := r.latest[job]
+	if !ok {
+		return Schemas.JobRun{}, ErrNotFound
+	}
+	return run, nil
+}
@@ -0,0 +1,326 @@
+package Repository
+
+import (
+	"backend/Mongo"
+	"backend/Schemas"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LikeRepository stores who liked which post or comment and keeps the
+// target's likeCount in step with it
+type LikeRepository interface {
+	// Like records the like and increments the target's counter. It reports
+	// false without changing anything when the user already likes the target,
+	// and ErrNotFound when the target does not exist.
+	Like(ctx context.Context, userID primitive.ObjectID, targetType string, targetID primitive.ObjectID) (bool, error)
+	// Unlike removes the like and decrements the target's counter. It reports
+	// false without changing anything when there was no like.
+	Unlike(ctx context.Context, userID primitive.ObjectID, targetType string, targetID primitive.ObjectID) (bool, error)
+	// LikedTargets returns which of targetIDs the user likes
+	LikedTargets(ctx context.Context, userID primitive.ObjectID, targetType string, targetIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error)
+	// DeleteByTarget removes every like of the targets, which are being
+	// deleted, and returns how many. Counters are left alone.
+	DeleteByTarget(ctx context.Context, targetType string, targetIDs []primitive.ObjectID) (int64, error)
+}
+
+// likeCounterCollection returns the collection holding targetType's likeCount
+func likeCounterCollection(targetType string) (string, error) {
+	switch targetType {
+	case Schemas.LikeTargetPost:
+		return PostsCollection, nil
+	case Schemas.LikeTargetComment:
+		return CommentsCollection, nil
+	default:
+		return "", fmt.Errorf("unknown like target %q", targetType)
+	}
+}
+
+// ─── MongoDB ────────────────────────────────────────────────────────────────
+
+type mongoLikeRepository struct{}
+
+func likeKey(userID primitive.ObjectID, targetType string, targetID primitive.ObjectID) bson.M {
+	return bson.M{"user_id": userID, "target_type": targetType, "target_id": targetID}
+}
+
+// inTransaction runs change in a transaction so the like and the counter
+// never disagree. Transactions need a replica set or sharded cluster, main
+// refuses to start on a standalone server.
+func (r *mongoLikeRepository) inTransaction(ctx context.Context, change func(sc mongo.SessionContext) (bool, error)) (bool, error) {
+	client, err := Mongo.GetMongoDB()
+	if err != nil {
+		return false, err
+	}
+	session, err := client.StartSession()
+	if err != nil {
+		return false, err
+	}
+	defer session.EndSession(ctx)
+
+	result, err := session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return change(sc)
+	})
+	if err != nil {
+		return false, err
+	}
+	return result.(bool), nil
+}
+
+// collections returns the likes collection and the one holding targetType's
+// likeCount
+func (r *mongoLikeRepository) collections(targetType string) (*mongo.Collection, *mongo.Collection, error) {
+	counterName, err := likeCounterCollection(targetType)
+	if err != nil {
+		return nil, nil, err
+	}
+	likes, err := collection(LikesCollection)
+	if err != nil {
+		return nil, nil, err
+	}
+	counter, err := collection(counterName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return likes, counter, nil
+}
+
+func (r *mongoLikeRepository) Like(ctx context.Context, userID primitive.ObjectID, targetType string, targetID primitive.ObjectID) (bool, error) {
+	likes, counter, err := r.collections(targetType)
+	if err != nil {
+		return false, err
+	}
+
+	changed, err := r.inTransaction(ctx, func(sc mongo.SessionContext) (bool, error) {
+		// Concurrent likes of a target conflict on its counter and one is
+		// retried, so this check sees the other's like even without the
+		// unique index
+		existing, err := likes.CountDocuments(sc, likeKey(userID, targetType, targetID), options.Count().SetLimit(1))
+		if err != nil || existing > 0 {
+			return false, err
+		}
+
+		result, err := counter.UpdateOne(sc, bson.M{"_id": targetID}, bson.M{"$inc": bson.M{"likeCount": 1}})
+		if err != nil {
+			return false, err
+		}
+		if result.MatchedCount == 0 {
+			return false, ErrNotFound
+		}
+
+		like := Schemas.Like{UserID: userID, TargetType: targetType, TargetID: targetID, CreatedAt: time.Now().UTC()}
+		_, err = likes.InsertOne(sc, like)
+		return err == nil, err
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		// Already liked, liking again is a no-op
+		return false, nil
+	}
+	return changed, err
+}
+
+func (r *mongoLikeRepository) Unlike(ctx context.Context, userID primitive.ObjectID, targetType string, targetID primitive.ObjectID) (bool, error) {
+	likes, counter, err := r.collections(targetType)
+	if err != nil {
+		return false, err
+	}
+
+	return r.inTransaction(ctx, func(sc mongo.SessionContext) (bool, error) {
+		result, err := likes.DeleteOne(sc, likeKey(userID, targetType, targetID))
+		if err != nil || result.DeletedCount == 0 {
+			return false, err
+		}
+
+		_, err = counter.UpdateOne(sc, bson.M{"_id": targetID}, bson.M{"$inc": bson.M{"likeCount": -1}})
+		return true, err
+	})
+}
+
+func (r *mongoLikeRepository) LikedTargets(ctx context.Context, userID primitive.ObjectID, targetType string, targetIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	liked := make(map[primitive.ObjectID]bool)
+	if len(targetIDs) == 0 {
+		return liked, nil
+	}
+	likes, err := collection(LikesCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	for start := 0; start < len(targetIDs); start += maxInBatch {
+		end := start + maxInBatch
+		if end > len(targetIDs) {
+			end = len(targetIDs)
+		}
+
+		filter := bson.M{"user_id": userID, "target_type": targetType, "target_id": bson.M{"$in": targetIDs[start:end]}}
+		cursor, err := likes.Find(ctx, filter, options.Find().SetProjection(bson.M{"target_id": 1}))
+		if err != nil {
+			return nil, err
+		}
+
+		var found []Schemas.Like
+		if err := cursor.All(ctx, &found); err != nil {
+			return nil, err
+		}
+		for _, like := range found {
+			liked[like.TargetID] = true
+		}
+	}
+	return liked, nil
+}
+
+func (r *mongoLikeRepository) DeleteByTarget(ctx context.Context, targetType string, targetIDs []primitive.ObjectID) (int64, error) {
+	likes, err := collection(LikesCollection)
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for start := 0; start < len(targetIDs); start += maxInBatch {
+		end := start + maxInBatch
+		if end > len(targetIDs) {
+			end = len(targetIDs)
+		}
+
+		filter := bson.M{"target_type": targetType, "target_id": bson.M{"$in": targetIDs[start:end]}}
+		result, err := likes.DeleteMany(ctx, filter)
+		if err != nil {
+			return deleted, err
+		}
+		deleted += result.DeletedCount
+	}
+	return deleted, nil
+}
+
+func (r *mongoLikeRepository) ensureIndexes(ctx context.Context) error {
+	likes, err := collection(LikesCollection)
+	if err != nil {
+		return err
+	}
+	_, err = likes.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "target_type", Value: 1}, {Key: "target_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		// DeleteByTarget looks likes up by target alone
+		{Keys: bson.D{{Key: "target_type", Value: 1}, {Key: "target_id", Value: 1}}},
+	})
+	return err
+}
+
+// ─── In memory ──────────────────────────────────────────────────────────────
+
+type memoryLikeKey struct {
+	userID     primitive.ObjectID
+	targetType string
+	targetID   primitive.ObjectID
+}
+
+type memoryLikeRepository struct {
+	mu       sync.Mutex
+	likes    map[memoryLikeKey]Schemas.Like
+	posts    *memoryPostRepository
+	comments *memoryCommentRepository
+}
+
+func newMemoryLikeRepository(posts *memoryPostRepository, comments *memoryCommentRepository) *memoryLikeRepository {
+	return &memoryLikeRepository{likes: make(map[memoryLikeKey]Schemas.Like), posts: posts, comments: comments}
+}
+
+// incrementCounter changes the target's likeCount, or fails with ErrNotFound.
+// Callers hold r.mu, so the like and the counter change together.
+func (r *memoryLikeRepository) incrementCounter(ctx context.Context, targetType string, targetID primitive.ObjectID, delta int) error {
+	var matched bool
+	var err error
+	switch targetType {
+	case Schemas.LikeTargetPost:
+		matched, err = r.posts.IncrementLikes(ctx, targetID, delta)
+	case Schemas.LikeTargetComment:
+		matched, err = r.comments.IncrementLikes(ctx, targetID, delta)
+	default:
+		return fmt.Errorf("unknown like target %q", targetType)
+	}
+	if err == nil && !matched {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (r *memoryLikeRepository) Like(ctx context.Context, userID primitive.ObjectID, targetType string, targetID primitive.ObjectID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := memoryLikeKey{userID: userID, targetType: targetType, targetID: targetID}
+	if _, exists := r.likes[key]; exists {
+		return false, nil
+	}
+	if err := r.incrementCounter(ctx, targetType, targetID, 1); err != nil {
+		return false, err
+	}
+
+	r.likes[key] = Schemas.Like{
+		ID:         primitive.NewObjectID(),
+		UserID:     userID,
+		TargetType: targetType,
+		TargetID:   targetID,
+		CreatedAt:  time.Now().UTC(),
+	}
+	return true, nil
+}
+
+func (r *memoryLikeRepository) Unlike(ctx context.Context, userID primitive.ObjectID, targetType string, targetID primitive.ObjectID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := memoryLikeKey{userID: userID, targetType: targetType, targetID: targetID}
+	if _, exists := r.likes[key]; !exists {
+		return false, nil
+	}
+	// A target deleted meanwhile has no counter left, the like goes anyway
+	if err := r.incrementCounter(ctx, targetType, targetID, -1); err != nil && !errors.Is(err, ErrNotFound) {
+		return false, err
+	}
+
+	delete(r.likes, key)
+	return true, nil
+}
+
+func (r *memoryLikeRepository) LikedTargets(ctx context.Context, userID primitive.ObjectID, targetType string, targetIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	liked := make(map[primitive.ObjectID]bool)
+	for _, targetID := range targetIDs {
+		if _, exists := r.likes[memoryLikeKey{userID: userID, targetType: targetType, targetID: targetID}]; exists {
+			liked[targetID] = true
+		}
+	}
+	return liked, nil
+}
+
+func (r *memoryLikeRepository) DeleteByTarget(ctx context.Context, targetType string, targetIDs []primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	targets := make(map[primitive.ObjectID]bool, len(targetIDs))
+	for _, targetID := range targetIDs {
+		targets[targetID] = true
+	}
+
+	var deleted int64
+	for key := range r.likes {
+		if key.targetType == targetType && targets[key.targetID] {
+			delete(r.likes, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
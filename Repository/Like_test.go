@@ -0,0 +1,71 @@
+package Repository
+
+import (
+	"backend/Schemas"
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryLikes(t *testing.T) {
+	ctx := context.Background()
+	repositories := NewMemoryRepositories()
+	likes := repositories.Likes
+
+	postID, err := repositories.Posts.Create(ctx, Schemas.Post{Problem: "Like me"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, bob := primitive.NewObjectID(), primitive.NewObjectID()
+
+	tests := []struct {
+		name        string
+		like        bool
+		user        primitive.ObjectID
+		target      primitive.ObjectID
+		wantChanged bool
+		wantErr     error
+		wantCount   int
+	}{
+		{"like", true, alice, postID, true, nil, 1},
+		{"like again", true, alice, postID, false, nil, 1},
+		{"second user", true, bob, postID, true, nil, 2},
+		{"unlike", false, alice, postID, true, nil, 1},
+		{"unlike again", false, alice, postID, false, nil, 1},
+		{"missing target", true, alice, primitive.NewObjectID(), false, ErrNotFound, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change := likes.Unlike
+			if tt.like {
+				change = likes.Like
+			}
+			changed, err := change(ctx, tt.user, Schemas.LikeTargetPost, tt.target)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("changed = %v, want %v", changed, tt.wantChanged)
+			}
+
+			post, err := repositories.Posts.FindByID(ctx, postID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if post.LikeCount != tt.wantCount {
+				t.Errorf("likeCount = %d, want %d", post.LikeCount, tt.wantCount)
+			}
+		})
+	}
+
+	liked, err := likes.LikedTargets(ctx, bob, Schemas.LikeTargetPost, []primitive.ObjectID{postID, primitive.NewObjectID()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(liked) != 1 || !liked[postID] {
+		t.Errorf("LikedTargets = %v, want only the post", liked)
+	}
+}
@@ -0,0 +1,126 @@
+package Repository
+
+import (
+	"backend/Schemas"
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LoginAttemptRepository tracks failed logins per key (username or IP)
+type LoginAttemptRepository interface {
+	// Get returns the failures of key, ErrNotFound when there are none
+	Get(ctx context.Context, key string) (Schemas.LoginAttempts, error)
+	// RecordFailure counts a failure at now and returns the new state. Failures
+	// older than resetAfter are forgotten first.
+	RecordFailure(ctx context.Context, key string, now time.Time, resetAfter time.Duration) (Schemas.LoginAttempts, error)
+	// Reset forgets the failures of key
+	Reset(ctx context.Context, key string) error
+}
+
+// ─── MongoDB ────────────────────────────────────────────────────────────────
+
+type mongoLoginAttemptRepository struct{}
+
+func (r *mongoLoginAttemptRepository) Get(ctx context.Context, key string) (Schemas.LoginAttempts, error) {
+	var attempts Schemas.LoginAttempts
+	coll, err := usersDatabaseCollection(LoginAttemptsCollection)
+	if err != nil {
+		return Schemas.LoginAttempts{}, err
+	}
+	err = coll.FindOne(ctx, bson.M{"_id": key}).Decode(&attempts)
+	return attempts, notFound(err)
+}
+
+func (r *mongoLoginAttemptRepository) RecordFailure(ctx context.Context, key string, now time.Time, resetAfter time.Duration) (Schemas.LoginAttempts, error) {
+	// An update pipeline restarts the count when the last failure is stale,
+	// in the same atomic operation as the increment
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"failures": bson.M{"$cond": bson.A{
+			bson.M{"$lt": bson.A{"$last_failure", now.Add(-resetAfter)}},
+			1,
+			bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$failures", 0}}, 1}},
+		}},
+		"last_failure": now,
+	}}}}
+
+	var attempts Schemas.LoginAttempts
+	coll, err := usersDatabaseCollection(LoginAttemptsCollection)
+	if err != nil {
+		return Schemas.LoginAttempts{}, err
+	}
+	err = coll.
+		FindOneAndUpdate(ctx, bson.M{"_id": key}, update, options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).
+		Decode(&attempts)
+	return attempts, err
+}
+
+func (r *mongoLoginAttemptRepository) Reset(ctx context.Context, key string) error {
+	coll, err := usersDatabaseCollection(LoginAttemptsCollection)
+	if err != nil {
+		return err
+	}
+	_, err = coll.DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
+
+func (r *mongoLoginAttemptRepository) ensureIndexes(ctx context.Context) error {
+	// Stale counters are dropped after a day
+	coll, err := usersDatabaseCollection(LoginAttemptsCollection)
+	if err != nil {
+		return err
+	}
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "last_failure", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32((24 * time.Hour).Seconds())),
+	})
+	return err
+}
+
+// ─── In memory ──────────────────────────────────────────────────────────────
+
+type memoryLoginAttemptRepository struct {
+	mu       sync.Mutex
+	attempts map[string]Schemas.LoginAttempts
+}
+
+func newMemoryLoginAttemptRepository() *memoryLoginAttemptRepository {
+	return &memoryLoginAttemptRepository{attempts: make(map[string]Schemas.LoginAttempts)}
+}
+
+func (r *memoryLoginAttemptRepository) Get(ctx context.Context, key string) (Schemas.LoginAttempts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempts, ok := r.attempts[key]
+	if !ok {
+		return Schemas.LoginAttempts{}, ErrNotFound
+	}
+	return attempts, nil
+}
+
+func (r *memoryLoginAttemptRepository) RecordFailure(ctx context.Context, key string, now time.Time, resetAfter time.Duration) (Schemas.LoginAttempts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempts, ok := r.attempts[key]
+	if !ok || attempts.LastFailure.Before(now.Add(-resetAfter)) {
+		attempts = Schemas.LoginAttempts{Key: key}
+	}
+	attempts.Failures++
+	attempts.LastFailure = now
+	r.attempts[key] = attempts
+	return attempts, nil
+}
+
+func (r *memoryLoginAttemptRepository) Reset(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, key)
+	return nil
+}
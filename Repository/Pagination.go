@@ -0,0 +1,142 @@
+package Repository
+
+import (
+	"backend/Schemas"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PostSort is the order of a post listing. Every order is descending and
+// ties are broken by _id, so the order is stable between pages.
+type PostSort string
+
+const (
+	SortNewest        PostSort = "newest"
+	SortMostLiked     PostSort = "most_liked"
+	SortMostCommented PostSort = "most_commented"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// ErrInvalidCursor is returned when a cursor cannot be decoded or was
+// produced for a different sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ParsePostSort validates a sort query parameter, defaulting to newest
+func ParsePostSort(value string) (PostSort, bool) {
+	switch PostSort(value) {
+	case "", SortNewest:
+		return SortNewest, true
+	case SortMostLiked, SortMostCommented:
+		return PostSort(value), true
+	}
+	return "", false
+}
+
+// PostQuery describes one page of a post listing
+type PostQuery struct {
+	// TagIDs restricts the listing to posts carrying at least one of the
+	// tags. nil means no restriction, an empty slice matches nothing.
+	TagIDs []string
+	Sort   PostSort
+	Limit  int
+	// Cursor is the opaque next_cursor of the previous page, or empty
+	Cursor string
+}
+
+// PostPage is one page of posts and the cursor of the following page, which
+// is empty on the last page
+type PostPage struct {
+	Posts      []Schemas.Post
+	NextCursor string
+}
+
+// postCursor is the decoded form of the opaque cursor: the sort value and
+// _id of the last post of the previous page
+type postCursor struct {
+	Sort  PostSort           `json:"s"`
+	Value int                `json:"v"`
+	ID    primitive.ObjectID `json:"id"`
+}
+
+func encodeCursor(cursor postCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string, sort PostSort) (*postCursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor postCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Sort != sort || cursor.ID.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// sortValue is the value a post is ordered by for sort, besides its _id
+func sortValue(post Schemas.Post, sort PostSort) int {
+	switch sort {
+	case SortMostLiked:
+		return post.LikeCount
+	case SortMostCommented:
+		return post.CommentCount
+	}
+	return 0
+}
+
+// sortField is the document field a post is ordered by for sort
+func sortField(sort PostSort) string {
+	switch sort {
+	case SortMostLiked:
+		return "likeCount"
+	case SortMostCommented:
+		return "commentCount"
+	}
+	return ""
+}
+
+// isAfter reports whether post comes after cursor in the listing order
+func (cursor *postCursor) isAfter(post Schemas.Post) bool {
+	value := sortValue(post, cursor.Sort)
+	if value != cursor.Value {
+		return value < cursor.Value
+	}
+	return post.ID.Hex() < cursor.ID.Hex()
+}
+
+// normalizeLimit clamps limit to [1, MaxPageSize], defaulting to DefaultPageSize
+func normalizeLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		return MaxPageSize
+	}
+	return limit
+}
+
+// buildPage trims posts fetched with limit+1 to limit and computes the next
+// cursor when there are more
+func buildPage(posts []Schemas.Post, limit int, sort PostSort) PostPage {
+	page := PostPage{Posts: posts}
+	if len(posts) > limit {
+		page.Posts = posts[:limit]
+		last := page.Posts[limit-1]
+		page.NextCursor = encodeCursor(postCursor{Sort: sort, Value: sortValue(last, sort), ID: last.ID})
+	}
+	return page
+}
@@ -0,0 +1,162 @@
+package Repository
+
+import (
+	"backend/Schemas"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := primitive.NewObjectID()
+
+	tests := []struct {
+		name   string
+		cursor postCursor
+	}{
+		{"newest", postCursor{Sort: SortNewest, ID: id}},
+		{"most liked", postCursor{Sort: SortMostLiked, Value: 42, ID: id}},
+		{"most commented", postCursor{Sort: SortMostCommented, Value: 0, ID: id}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := decodeCursor(encodeCursor(tt.cursor), tt.cursor.Sort)
+			if err != nil {
+				t.Fatalf("decodeCursor: %v", err)
+			}
+			if *decoded != tt.cursor {
+				t.Errorf("decoded %+v, want %+v", *decoded, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	valid := encodeCursor(postCursor{Sort: SortMostLiked, Value: 3, ID: primitive.NewObjectID()})
+
+	tests := []struct {
+		name  string
+		value string
+		sort  PostSort
+	}{
+		{"not base64", "%%%", SortMostLiked},
+		{"not JSON", base64.RawURLEncoding.EncodeToString([]byte("nope")), SortMostLiked},
+		{"other sort", valid, SortNewest},
+		{"missing id", encodeCursor(postCursor{Sort: SortNewest}), SortNewest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCursor(tt.value, tt.sort); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("err = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+
+	if cursor, err := decodeCursor("", SortNewest); cursor != nil || err != nil {
+		t.Errorf("empty cursor = %v, %v, want nil, nil", cursor, err)
+	}
+}
+
+func TestParsePostSort(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   PostSort
+		wantOK bool
+	}{
+		{"", SortNewest, true},
+		{"newest", SortNewest, true},
+		{"most_liked", SortMostLiked, true},
+		{"most_commented", SortMostCommented, true},
+		{"oldest", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParsePostSort(tt.value)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("ParsePostSort(%q) = %q, %v, want %q, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestNormalizeLimit(t *testing.T) {
+	tests := []struct {
+		limit, want int
+	}{
+		{0, DefaultPageSize},
+		{-5, DefaultPageSize},
+		{1, 1},
+		{MaxPageSize, MaxPageSize},
+		{MaxPageSize + 1, MaxPageSize},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeLimit(tt.limit); got != tt.want {
+			t.Errorf("normalizeLimit(%d) = %d, want %d", tt.limit, got, tt.want)
+		}
+	}
+}
+
+// Walking every page returns each post exactly once and in order, also when
+// posts share a sort value
+func TestFindPageWalksAllPosts(t *testing.T) {
+	ctx := context.Background()
+	posts := newMemoryPostRepository()
+	likes := []int{5, 3, 5, 0, 3, 5, 1}
+	for _, count := range likes {
+		if _, err := posts.Create(ctx, Schemas.Post{Problem: "p", LikeCount: count}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		sort  PostSort
+		limit int
+	}{
+		{SortNewest, 1},
+		{SortNewest, 3},
+		{SortMostLiked, 2},
+		{SortMostLiked, 10},
+		{SortMostCommented, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s by %d", tt.sort, tt.limit), func(t *testing.T) {
+			var seen []Schemas.Post
+			cursor := ""
+			for pages := 0; ; pages++ {
+				if pages > len(likes) {
+					t.Fatal("pagination does not end")
+				}
+				page, err := posts.FindPage(ctx, PostQuery{Sort: tt.sort, Limit: tt.limit, Cursor: cursor})
+				if err != nil {
+					t.Fatal(err)
+				}
+				seen = append(seen, page.Posts...)
+				if page.NextCursor == "" {
+					break
+				}
+				cursor = page.NextCursor
+			}
+
+			if len(seen) != len(likes) {
+				t.Fatalf("saw %d posts, want %d", len(seen), len(likes))
+			}
+			ids := map[primitive.ObjectID]bool{}
+			for i, post := range seen {
+				if ids[post.ID] {
+					t.Fatalf("post %s listed twice", post.ID.Hex())
+				}
+				ids[post.ID] = true
+				if i > 0 && sortValue(seen[i-1], tt.sort) < sortValue(post, tt.sort) {
+					t.Fatalf("post %d is out of order", i)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,455 @@
+package Repository
+
+import (
+	"backend/Schemas"
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PostRepository stores forum posts
+type PostRepository interface {
+	FindByID(ctx context.Context, id primitive.ObjectID) (Schemas.Post, error)
+	// FindPage returns one page of posts in the order and position of query
+	FindPage(ctx context.Context, query PostQuery) (PostPage, error)
+	FindUnlocked(ctx context.Context) ([]Schemas.Post, error)
+	Create(ctx context.Context, post Schemas.Post) (primitive.ObjectID, error)
+	// Delete reports whether a post was deleted
+	Delete(ctx context.Context, id primitive.ObjectID) (bool, error)
+	// IncrementLikes reports whether a post matched id
+	IncrementLikes(ctx context.Context, id primitive.ObjectID, delta int) (bool, error)
+	IncrementCommentCount(ctx context.Context, id primitive.ObjectID, delta int) error
+	// Lock and Unlock report whether a post matched id
+	Lock(ctx context.Context, id primitive.ObjectID, reason string, lockedBy string, at time.Time) (bool, error)
+	Unlock(ctx context.Context, id primitive.ObjectID) (bool, error)
+	// SetPinned reports whether a post matched id
+	SetPinned(ctx context.Context, id primitive.ObjectID, pinned bool) (bool, error)
+}
+
+// ─── MongoDB ────────────────────────────────────────────────────────────────
+
+type mongoPostRepository struct{}
+
+func (r *mongoPostRepository) FindByID(ctx context.Context, id primitive.ObjectID) (Schemas.Post, error) {
+	var post Schemas.Post
+	coll, err := collection(PostsCollection)
+	if err != nil {
+		return Schemas.Post{}, err
+	}
+	err = coll.FindOne(ctx, bson.M{"_id": id}).Decode(&post)
+	return post, notFound(err)
+}
+
+func (r *mongoPostRepository) FindPage(ctx context.Context, query PostQuery) (PostPage, error) {
+	limit := normalizeLimit(query.Limit)
+	cursor, err := decodeCursor(query.Cursor, query.Sort)
+	if err != nil {
+		return PostPage{}, err
+	}
+
+	pipeline := mongo.Pipeline{}
+	if query.TagIDs != nil {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{"tags": bson.M{"$in": query.TagIDs}}}})
+	}
+
+	// Sort on the stored counters, which ensureIndexes backfills and indexes
+	field := sortField(query.Sort)
+	sortBy := bson.D{{Key: "_id", Value: -1}}
+	if field != "" {
+		sortBy = bson.D{{Key: field, Value: -1}, {Key: "_id", Value: -1}}
+	}
+
+	if cursor != nil {
+		after := bson.M{"_id": bson.M{"$lt": cursor.ID}}
+		if field != "" {
+			after = bson.M{"$or": bson.A{
+				bson.M{field: bson.M{"$lt": cursor.Value}},
+				bson.M{field: cursor.Value, "_id": bson.M{"$lt": cursor.ID}},
+			}}
+		}
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: after}})
+	}
+
+	pipeline = append(pipeline,
+		bson.D{{Key: "$sort", Value: sortBy}},
+		bson.D{{Key: "$limit", Value: limit + 1}},
+	)
+
+	coll, err := collection(PostsCollection)
+	if err != nil {
+		return PostPage{}, err
+	}
+	result, err := coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return PostPage{}, err
+	}
+
+	posts := make([]Schemas.Post, 0, limit+1)
+	if err := result.All(ctx, &posts); err != nil {
+		return PostPage{}, err
+	}
+	return buildPage(posts, limit, query.Sort), nil
+}
+
+func (r *mongoPostRepository) FindUnlocked(ctx context.Context) ([]Schemas.Post, error) {
+	// Posts where locked is false or null
+	return r.find(ctx, bson.M{
+		"$or": []bson.M{
+			{"locked": bson.M{"$eq": false}},
+			{"locked": bson.M{"$eq": nil}},
+		},
+	})
+}
+
+func (r *mongoPostRepository) find(ctx context.Context, filter bson.M) ([]Schemas.Post, error) {
+	coll, err := collection(PostsCollection)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := coll.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	posts := make([]Schemas.Post, 0)
+	if err := cursor.All(ctx, &posts); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+func (r *mongoPostRepository) Create(ctx context.Context, post Schemas.Post) (primitive.ObjectID, error) {
+	coll, err := collection(PostsCollection)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	result, err := coll.InsertOne(ctx, post)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	id, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return primitive.NilObjectID, errors.New("inserted post ID is not an ObjectID")
+	}
+	return id, nil
+}
+
+func (r *mongoPostRepository) Delete(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	coll, err := collection(PostsCollection)
+	if err != nil {
+		return false, err
+	}
+	result, err := coll.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+func (r *mongoPostRepository) IncrementLikes(ctx context.Context, id primitive.ObjectID, delta int) (bool, error) {
+	coll, err := collection(PostsCollection)
+	if err != nil {
+		return false, err
+	}
+	result, err := coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"likeCount": delta}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+func (r *mongoPostRepository) IncrementCommentCount(ctx context.Context, id primitive.ObjectID, delta int) error {
+	coll, err := collection(PostsCollection)
+	if err != nil {
+		return err
+	}
+	_, err = coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"commentCount": delta}})
+	return err
+}
+
+func (r *mongoPostRepository) Lock(ctx context.Context, id primitive.ObjectID, reason string, lockedBy string, at time.Time) (bool, error) {
+	coll, err := collection(PostsCollection)
+	if err != nil {
+		return false, err
+	}
+	result, err := coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"locked":      true,
+		"lock_reason": reason,
+		"locked_by":   lockedBy,
+		"locked_at":   at,
+	}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+func (r *mongoPostRepository) Unlock(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	coll, err := collection(PostsCollection)
+	if err != nil {
+		return false, err
+	}
+	result, err := coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set":   bson.M{"locked": false},
+		"$unset": bson.M{"lock_reason": "", "locked_by": "", "locked_at": ""},
+	})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+func (r *mongoPostRepository) SetPinned(ctx context.Context, id primitive.ObjectID, pinned bool) (bool, error) {
+	coll, err := collection(PostsCollection)
+	if err != nil {
+		return false, err
+	}
+	result, err := coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"pinned": pinned}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// ensureIndexes backfills the counters listings are sorted by and indexes
+// them together with _id, the tie breaker
+func (r *mongoPostRepository) ensureIndexes(ctx context.Context) error {
+	if err := r.backfillCounters(ctx); err != nil {
+		return err
+	}
+
+	coll, err := collection(PostsCollection)
+	if err != nil {
+		return err
+	}
+	_, err = coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "likeCount", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "commentCount", Value: -1}, {Key: "_id", Value: -1}}},
+	})
+	return err
+}
+
+// backfillCounters fills in the counters of posts stored before they
+// existed: likeCount becomes 0 and commentCount the number of comments of the
+// post. Posts that have both are left alone, so the work is only done once.
+func (r *mongoPostRepository) backfillCounters(ctx context.Context) error {
+	posts, err := collection(PostsCollection)
+	if err != nil {
+		return err
+	}
+	_, err = posts.UpdateMany(ctx, bson.M{"likeCount": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"likeCount": 0}})
+	if err != nil {
+		return err
+	}
+
+	cursor, err := posts.Find(ctx, bson.M{"commentCount": bson.M{"$exists": false}}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return err
+	}
+	var missing []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &missing); err != nil {
+		return err
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	postIDs := make([]string, len(missing))
+	for i, post := range missing {
+		postIDs[i] = post.ID.Hex()
+	}
+	counts, err := (&mongoCommentRepository{}).CountByPostIDs(ctx, postIDs)
+	if err != nil {
+		return err
+	}
+
+	updates := make([]mongo.WriteModel, len(missing))
+	for i, post := range missing {
+		updates[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": post.ID, "commentCount": bson.M{"$exists": false}}).
+			SetUpdate(bson.M{"$set": bson.M{"commentCount": counts[postIDs[i]]}})
+	}
+	if _, err := posts.BulkWrite(ctx, updates, options.BulkWrite().SetOrdered(false)); err != nil {
+		return err
+	}
+
+	slog.Info("Backfilled comment counts", "posts", len(missing))
+	return nil
+}
+
+// ─── In memory ──────────────────────────────────────────────────────────────
+
+type memoryPostRepository struct {
+	mu    sync.RWMutex
+	posts map[primitive.ObjectID]Schemas.Post
+	order []primitive.ObjectID
+}
+
+func newMemoryPostRepository() *memoryPostRepository {
+	return &memoryPostRepository{posts: make(map[primitive.ObjectID]Schemas.Post)}
+}
+
+func (r *memoryPostRepository) FindByID(ctx context.Context, id primitive.ObjectID) (Schemas.Post, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	post, ok := r.posts[id]
+	if !ok {
+		return Schemas.Post{}, ErrNotFound
+	}
+	return post, nil
+}
+
+func (r *memoryPostRepository) FindPage(ctx context.Context, query PostQuery) (PostPage, error) {
+	limit := normalizeLimit(query.Limit)
+	cursor, err := decodeCursor(query.Cursor, query.Sort)
+	if err != nil {
+		return PostPage{}, err
+	}
+
+	posts := r.filter(func(post Schemas.Post) bool {
+		return query.TagIDs == nil || containsAny(post.Tags, query.TagIDs)
+	})
+
+	sort.Slice(posts, func(i, j int) bool {
+		left, right := sortValue(posts[i], query.Sort), sortValue(posts[j], query.Sort)
+		if left != right {
+			return left > right
+		}
+		return posts[i].ID.Hex() > posts[j].ID.Hex()
+	})
+
+	page := make([]Schemas.Post, 0, limit+1)
+	for _, post := range posts {
+		if cursor != nil && !cursor.isAfter(post) {
+			continue
+		}
+		page = append(page, post)
+		if len(page) > limit {
+			break
+		}
+	}
+	return buildPage(page, limit, query.Sort), nil
+}
+
+func (r *memoryPostRepository) FindUnlocked(ctx context.Context) ([]Schemas.Post, error) {
+	return r.filter(func(post Schemas.Post) bool {
+		return !post.Locked
+	}), nil
+}
+
+func (r *memoryPostRepository) filter(keep func(Schemas.Post) bool) []Schemas.Post {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	posts := make([]Schemas.Post, 0)
+	for _, id := range r.order {
+		if post, ok := r.posts[id]; ok && keep(post) {
+			posts = append(posts, post)
+		}
+	}
+	return posts
+}
+
+func (r *memoryPostRepository) Create(ctx context.Context, post Schemas.Post) (primitive.ObjectID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if post.ID.IsZero() {
+		post.ID = primitive.NewObjectID()
+	}
+	post.Comments = nil
+	r.posts[post.ID] = post
+	r.order = append(r.order, post.ID)
+	return post.ID, nil
+}
+
+func (r *memoryPostRepository) Delete(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.posts[id]; !ok {
+		return false, nil
+	}
+	delete(r.posts, id)
+	for i, orderedID := range r.order {
+		if orderedID == id {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	return true, nil
+}
+
+func (r *memoryPostRepository) IncrementLikes(ctx context.Context, id primitive.ObjectID, delta int) (bool, error) {
+	return r.update(id, func(post *Schemas.Post) {
+		post.LikeCount += delta
+	}), nil
+}
+
+func (r *memoryPostRepository) IncrementCommentCount(ctx context.Context, id primitive.ObjectID, delta int) error {
+	r.update(id, func(post *Schemas.Post) {
+		post.CommentCount += delta
+	})
+	return nil
+}
+
+func (r *memoryPostRepository) Lock(ctx context.Context, id primitive.ObjectID, reason string, lockedBy string, at time.Time) (bool, error) {
+	return r.update(id, func(post *Schemas.Post) {
+		post.Locked = true
+		post.LockReason = reason
+		post.LockedBy = lockedBy
+		post.LockedAt = &at
+	}), nil
+}
+
+func (r *memoryPostRepository) Unlock(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	return r.update(id, func(post *Schemas.Post) {
+		post.Locked = false
+		post.LockReason = ""
+		post.LockedBy = ""
+		post.LockedAt = nil
+	}), nil
+}
+
+func (r *memoryPostRepository) SetPinned(ctx context.Context, id primitive.ObjectID, pinned bool) (bool, error) {
+	return r.update(id, func(post *Schemas.Post) {
+		post.Pinned = pinned
+	}), nil
+}
+
+// update applies change to the post with id and reports whether it exists
+func (r *memoryPostRepository) update(id primitive.ObjectID, change func(*Schemas.Post)) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	post, ok := r.posts[id]
+	if !ok {
+		return false
+	}
+	change(&post)
+	r.posts[id] = post
+	return true
+}
+
+func containsAny(values []string, wanted []string) bool {
+	for _, value := range values {
+		for _, w := range wanted {
+			if value == w {
+				return true
+			}
+		}
+	}
+	return false
+}
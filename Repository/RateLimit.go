@@ -0,0 +1,142 @@
+package Repository
+
+import (
+	"backend/Schemas"
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TokenBucket holds up to Capacity tokens and regains RefillPerSecond of
+// them every second. A missing bucket is full.
+type TokenBucket struct {
+	Capacity        float64
+	RefillPerSecond float64
+}
+
+// RateLimitRepository stores token buckets for the rate limiter
+type RateLimitRepository interface {
+	// Take refills the bucket of key up to now and takes one token from it
+	// when there is one. The returned bucket tells whether it did.
+	Take(ctx context.Context, key string, bucket TokenBucket, now time.Time) (Schemas.RateLimitBucket, error)
+}
+
+// ─── MongoDB ────────────────────────────────────────────────────────────────
+
+type mongoRateLimitRepository struct{}
+
+func (r *mongoRateLimitRepository) Take(ctx context.Context, key string, bucket TokenBucket, now time.Time) (Schemas.RateLimitBucket, error) {
+	// Refill, decide and take in one update pipeline so concurrent requests
+	// from several instances cannot spend the same token
+	elapsed := bson.M{"$divide": bson.A{
+		bson.M{"$subtract": bson.A{now, bson.M{"$ifNull": bson.A{"$updated_at", now}}}},
+		1000,
+	}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"tokens": bson.M{"$min": bson.A{
+				bucket.Capacity,
+				bson.M{"$add": bson.A{
+					bson.M{"$ifNull": bson.A{"$tokens", bucket.Capacity}},
+					bson.M{"$multiply": bson.A{bson.M{"$max": bson.A{elapsed, 0}}, bucket.RefillPerSecond}},
+				}},
+			}},
+		}}},
+		{{Key: "$set", Value: bson.M{"allowed": bson.M{"$gte": bson.A{"$tokens", 1}}}}},
+		{{Key: "$set", Value: bson.M{
+			"tokens":     bson.M{"$cond": bson.A{"$allowed", bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}},
+			"updated_at": now,
+		}}},
+	}
+
+	var state Schemas.RateLimitBucket
+	coll, err := collection(RateLimitsCollection)
+	if err != nil {
+		return Schemas.RateLimitBucket{}, err
+	}
+	err = coll.
+		FindOneAndUpdate(ctx, bson.M{"_id": key}, update, options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).
+		Decode(&state)
+	return state, err
+}
+
+func (r *mongoRateLimitRepository) ensureIndexes(ctx context.Context) error {
+	// An idle bucket refills, dropping it after a day only forgets a full one
+	coll, err := collection(RateLimitsCollection)
+	if err != nil {
+		return err
+	}
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "updated_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32((24 * time.Hour).Seconds())),
+	})
+	return err
+}
+
+// ─── In memory ──────────────────────────────────────────────────────────────
+
+// How often the memory store forgets buckets that have refilled
+const rateLimitSweepInterval = time.Minute
+
+type memoryRateLimitBucket struct {
+	state  Schemas.RateLimitBucket
+	bucket TokenBucket
+}
+
+// full reports whether the bucket has refilled by now, so forgetting it
+// changes nothing
+func (b memoryRateLimitBucket) full(now time.Time) bool {
+	return b.state.Tokens+now.Sub(b.state.UpdatedAt).Seconds()*b.bucket.RefillPerSecond >= b.bucket.Capacity
+}
+
+type memoryRateLimitRepository struct {
+	mu        sync.Mutex
+	buckets   map[string]memoryRateLimitBucket
+	lastSweep time.Time
+}
+
+// NewMemoryRateLimitRepository returns a rate limit store that lives in
+// process memory, limiting each instance on its own
+func NewMemoryRateLimitRepository() RateLimitRepository {
+	return &memoryRateLimitRepository{buckets: make(map[string]memoryRateLimitBucket)}
+}
+
+func (r *memoryRateLimitRepository) Take(ctx context.Context, key string, bucket TokenBucket, now time.Time) (Schemas.RateLimitBucket, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now.Sub(r.lastSweep) >= rateLimitSweepInterval {
+		for k, b := range r.buckets {
+			if b.full(now) {
+				delete(r.buckets, k)
+			}
+		}
+		r.lastSweep = now
+	}
+
+	current, ok := r.buckets[key]
+	if !ok {
+		current.state = Schemas.RateLimitBucket{Key: key, Tokens: bucket.Capacity, UpdatedAt: now}
+	}
+	current.bucket = bucket
+
+	if elapsed := now.Sub(current.state.UpdatedAt).Seconds(); elapsed > 0 {
+		current.state.Tokens += elapsed * bucket.RefillPerSecond
+	}
+	if current.state.Tokens > bucket.Capacity {
+		current.state.Tokens = bucket.Capacity
+	}
+
+	current.state.Allowed = current.state.Tokens >= 1
+	if current.state.Allowed {
+		current.state.Tokens--
+	}
+	current.state.UpdatedAt = now
+
+	r.buckets[key] = current
+	return current.state, nil
+}
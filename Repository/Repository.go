@@ -0,0 +1,295 @@
+package Repository
+
+import (
+	"backend/Config"
+	"backend/Mongo"
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Collection names, kept in one place. The database names are configured.
+const (
+	PostsCollection      = "studenci_district"
+	CommentsCollection   = "melje_district"
+	TagsCollection       = "tags"
+	UsersCollection      = "users"
+	RoomsCollection      = "chat_rooms"
+	MessagesCollection   = "chat_messages"
+	JobRunsCollection    = "job_runs"
+	JobLeasesCollection  = "job_leases"
+	LikesCollection      = "likes"
+	RateLimitsCollection = "rate_limits"
+
+	// In the users database
+	SecurityEventsCollection = "security_events"
+	UserTokensCollection     = "user_tokens"
+	LoginAttemptsCollection  = "login_attempts"
+)
+
+// ErrNotFound is returned when a lookup matches no document
+var ErrNotFound = errors.New("not found")
+
+// ErrDuplicate is returned when an insert violates a unique constraint
+var ErrDuplicate = errors.New("duplicate")
+
+// DuplicateError is an ErrDuplicate that knows which field clashed
+type DuplicateError struct {
+	Field string
+}
+
+func (e *DuplicateError) Error() string {
+	return e.Field + " already exists"
+}
+
+func (e *DuplicateError) Is(target error) bool {
+	return target == ErrDuplicate
+}
+
+// Repositories groups every repository used by the handlers
+type Repositories struct {
+	Posts          PostRepository
+	Comments       CommentRepository
+	Users          UserRepository
+	Tags           TagRepository
+	Search         SearchRepository
+	Rooms          RoomRepository
+	Messages       MessageRepository
+	JobRuns        JobRunRepository
+	JobLeases      JobLeaseRepository
+	Likes          LikeRepository
+	SecurityEvents SecurityEventRepository
+	UserTokens     UserTokenRepository
+	LoginAttempts  LoginAttemptRepository
+	RateLimits     RateLimitRepository
+}
+
+// current is read without a lock by every accessor, currentMu only
+// serializes choosing the backend
+var (
+	current   atomic.Pointer[Repositories]
+	currentMu sync.Mutex
+	storage   = Config.Default().Storage
+)
+
+// Configure selects the storage backend and database names. It must be
+// called before the repositories are first used.
+func Configure(cfg Config.Storage) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+
+	storage = cfg
+	current.Store(nil)
+}
+
+// NewMongoRepositories returns repositories backed by MongoDB
+func NewMongoRepositories() *Repositories {
+	return &Repositories{
+		Posts:          &mongoPostRepository{},
+		Comments:       &mongoCommentRepository{},
+		Users:          &mongoUserRepository{},
+		Tags:           &mongoTagRepository{},
+		Search:         &mongoSearchRepository{},
+		Rooms:          &mongoRoomRepository{},
+		Messages:       &mongoMessageRepository{},
+		JobRuns:        &mongoJobRunRepository{},
+		JobLeases:      &mongoJobLeaseRepository{},
+		Likes:          &mongoLikeRepository{},
+		SecurityEvents: &mongoSecurityEventRepository{},
+		UserTokens:     &mongoUserTokenRepository{},
+		LoginAttempts:  &mongoLoginAttemptRepository{},
+		RateLimits:     &mongoRateLimitRepository{},
+	}
+}
+
+// NewMemoryRepositories returns empty repositories that live in process
+// memory, for tests and offline development.
+func NewMemoryRepositories() *Repositories {
+	posts := newMemoryPostRepository()
+	comments := newMemoryCommentRepository()
+
+	return &Repositories{
+		Posts:          posts,
+		Comments:       comments,
+		Users:          newMemoryUserRepository(),
+		Tags:           newMemoryTagRepository(),
+		Search:         &memorySearchRepository{posts: posts, comments: comments},
+		Rooms:          newMemoryRoomRepository(),
+		Messages:       newMemoryMessageRepository(),
+		JobRuns:        newMemoryJobRunRepository(),
+		JobLeases:      newMemoryJobLeaseRepository(),
+		Likes:          newMemoryLikeRepository(posts, comments),
+		SecurityEvents: &memorySecurityEventRepository{},
+		UserTokens:     newMemoryUserTokenRepository(),
+		LoginAttempts:  newMemoryLoginAttemptRepository(),
+		RateLimits:     NewMemoryRateLimitRepository(),
+	}
+}
+
+// indexer is implemented by repositories that need database indexes
+type indexer interface {
+	ensureIndexes(ctx context.Context) error
+}
+
+// EnsureIndexes creates the indexes every active repository relies on. It is
+// safe to call on every startup. A repository that fails does not stop the
+// others, the returned error joins every failure.
+func EnsureIndexes(ctx context.Context) error {
+	repositories := get()
+	var errs []error
+	// Comments come first, the post counter backfill looks them up by post
+	for _, repository := range []interface{}{
+		repositories.Comments,
+		repositories.Posts,
+		repositories.Users,
+		repositories.Tags,
+		repositories.Search,
+		repositories.Rooms,
+		repositories.Messages,
+		repositories.JobRuns,
+		repositories.Likes,
+		repositories.SecurityEvents,
+		repositories.UserTokens,
+		repositories.LoginAttempts,
+		repositories.RateLimits,
+	} {
+		if withIndexes, ok := repository.(indexer); ok {
+			errs = append(errs, withIndexes.ensureIndexes(ctx))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Use replaces the repositories returned by the accessors below
+func Use(repositories *Repositories) {
+	current.Store(repositories)
+}
+
+// get returns the active repositories, choosing the configured backend
+// (mongo or memory) on first use.
+func get() *Repositories {
+	if repositories := current.Load(); repositories != nil {
+		return repositories
+	}
+
+	currentMu.Lock()
+	defer currentMu.Unlock()
+
+	if current.Load() == nil {
+		switch storage.Backend {
+		case "memory":
+			slog.Warn("Using in-memory storage, data is lost on restart")
+			current.Store(NewMemoryRepositories())
+		default:
+			current.Store(NewMongoRepositories())
+		}
+	}
+	return current.Load()
+}
+
+func Posts() PostRepository {
+	return get().Posts
+}
+
+func Comments() CommentRepository {
+	return get().Comments
+}
+
+func Users() UserRepository {
+	return get().Users
+}
+
+func Tags() TagRepository {
+	return get().Tags
+}
+
+func Search() SearchRepository {
+	return get().Search
+}
+
+func Rooms() RoomRepository {
+	return get().Rooms
+}
+
+func Messages() MessageRepository {
+	return get().Messages
+}
+
+func JobRuns() JobRunRepository {
+	return get().JobRuns
+}
+
+func JobLeases() JobLeaseRepository {
+	return get().JobLeases
+}
+
+func Likes() LikeRepository {
+	return get().Likes
+}
+
+func SecurityEvents() SecurityEventRepository {
+	return get().SecurityEvents
+}
+
+func UserTokens() UserTokenRepository {
+	return get().UserTokens
+}
+
+func LoginAttempts() LoginAttemptRepository {
+	return get().LoginAttempts
+}
+
+func RateLimits() RateLimitRepository {
+	return get().RateLimits
+}
+
+// collection returns a collection of the configured database, it fails when
+// no MongoDB client can be created
+func collection(name string) (*mongo.Collection, error) {
+	client, err := Mongo.GetMongoDB()
+	if err != nil {
+		return nil, err
+	}
+	return client.Database(storage.Database).Collection(name), nil
+}
+
+func usersCollection() (*mongo.Collection, error) {
+	return usersDatabaseCollection(UsersCollection)
+}
+
+// usersDatabaseCollection returns a collection of the database holding users
+func usersDatabaseCollection(name string) (*mongo.Collection, error) {
+	client, err := Mongo.GetMongoDB()
+	if err != nil {
+		return nil, err
+	}
+	return client.Database(storage.UsersDatabase).Collection(name), nil
+}
+
+// maxInBatch is the largest number of values sent in a single $in query
+const maxInBatch = 1000
+
+// batches splits values into consecutive slices of at most size elements
+func batches(values []string, size int) [][]string {
+	var result [][]string
+	for len(values) > size {
+		result = append(result, values[:size])
+		values = values[size:]
+	}
+	if len(values) > 0 {
+		result = append(result, values)
+	}
+	return result
+}
+
+// notFound maps the driver's "no documents" error to ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,183 @@
+package Repository
+
+import (
+	"backend/Schemas"
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryNotFound(t *testing.T) {
+	ctx := context.Background()
+	repositories := NewMemoryRepositories()
+	missing := primitive.NewObjectID()
+
+	tests := []struct {
+		name string
+		find func() error
+	}{
+		{"post", func() error { _, err := repositories.Posts.FindByID(ctx, missing); return err }},
+		{"comment", func() error { _, err := repositories.Comments.FindByID(ctx, missing); return err }},
+		{"user by id", func() error { _, err := repositories.Users.FindByID(ctx, missing); return err }},
+		{"user by username", func() error { _, err := repositories.Users.FindByUsername(ctx, "nobody"); return err }},
+		{"user by email", func() error { _, err := repositories.Users.FindByEmail(ctx, "nobody@example.com"); return err }},
+		{"tag by id", func() error { _, err := repositories.Tags.FindByID(ctx, missing.Hex()); return err }},
+		{"tag by name", func() error { _, err := repositories.Tags.FindByName(ctx, "nothing"); return err }},
+		{"room", func() error { _, err := repositories.Rooms.FindByName(ctx, "nowhere"); return err }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.find(); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("err = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestMemoryDelete(t *testing.T) {
+	ctx := context.Background()
+	repositories := NewMemoryRepositories()
+
+	postID, err := repositories.Posts.Create(ctx, Schemas.Post{Problem: "Delete me"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commentID, err := repositories.Comments.Create(ctx, Schemas.Comment{PostId: postID.Hex(), Description: "Delete me"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repositories.Rooms.Create(ctx, Schemas.ChatRoom{Name: "general"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		delete func() (bool, error)
+		want   bool
+	}{
+		{"post", func() (bool, error) { return repositories.Posts.Delete(ctx, postID) }, true},
+		{"post again", func() (bool, error) { return repositories.Posts.Delete(ctx, postID) }, false},
+		{"comment", func() (bool, error) { return repositories.Comments.Delete(ctx, commentID) }, true},
+		{"comment again", func() (bool, error) { return repositories.Comments.Delete(ctx, commentID) }, false},
+		{"room", func() (bool, error) { return repositories.Rooms.Delete(ctx, "general") }, true},
+		{"room again", func() (bool, error) { return repositories.Rooms.Delete(ctx, "general") }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deleted, err := tt.delete()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if deleted != tt.want {
+				t.Fatalf("deleted = %v, want %v", deleted, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryUserDuplicates(t *testing.T) {
+	ctx := context.Background()
+	users := NewMemoryRepositories().Users
+
+	existingID, err := users.Create(ctx, Schemas.User{Name: "Alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		user      Schemas.User
+		wantField string
+	}{
+		{"same username", Schemas.User{Name: "Alice", Email: "other@example.com"}, "username"},
+		{"username in other case", Schemas.User{Name: "ALICE", Email: "other@example.com"}, "username"},
+		{"email in other case", Schemas.User{Name: "Bob", Email: "Alice@Example.com"}, "email"},
+		{"same id", Schemas.User{ID: existingID, Name: "Bob", Email: "bob@example.com"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := users.Create(ctx, tt.user)
+			if !errors.Is(err, ErrDuplicate) {
+				t.Fatalf("err = %v, want ErrDuplicate", err)
+			}
+			var duplicate *DuplicateError
+			if errors.As(err, &duplicate) != (tt.wantField != "") {
+				t.Fatalf("err = %#v, want DuplicateError only for a clashing field", err)
+			}
+			if duplicate != nil && duplicate.Field != tt.wantField {
+				t.Fatalf("field = %q, want %q", duplicate.Field, tt.wantField)
+			}
+		})
+	}
+
+	user, err := users.FindByUsername(ctx, "aLiCe")
+	if err != nil || user.ID != existingID {
+		t.Fatalf("FindByUsername ignoring case = %v, %v, want %v", user.ID, err, existingID)
+	}
+}
+
+func TestMemoryRoomDuplicates(t *testing.T) {
+	ctx := context.Background()
+	rooms := NewMemoryRepositories().Rooms
+
+	if _, err := rooms.Create(ctx, Schemas.ChatRoom{Name: "general"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rooms.Create(ctx, Schemas.ChatRoom{Name: "general"}); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("err = %v, want ErrDuplicate", err)
+	}
+}
+
+func TestMemoryOrdering(t *testing.T) {
+	ctx := context.Background()
+	repositories := NewMemoryRepositories()
+
+	postID := primitive.NewObjectID().Hex()
+	for _, description := range []string{"first", "second", "third"} {
+		if _, err := repositories.Comments.Create(ctx, Schemas.Comment{PostId: postID, Description: description}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"random", "general", "help"} {
+		if _, err := repositories.Rooms.Create(ctx, Schemas.ChatRoom{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	comments, err := repositories.Comments.FindByPostID(ctx, postID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var descriptions []string
+	for _, comment := range comments {
+		descriptions = append(descriptions, comment.Description)
+	}
+	assertOrder(t, "comments", descriptions, []string{"first", "second", "third"})
+
+	rooms, err := repositories.Rooms.FindAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, room := range rooms {
+		names = append(names, room.Name)
+	}
+	assertOrder(t, "rooms", names, []string{"general", "help", "random"})
+}
+
+func assertOrder(t *testing.T, what string, got []string, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s = %v, want %v", what, got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("%s = %v, want %v", what, got, want)
+		}
+	}
+}
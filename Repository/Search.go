@@ -0,0 +1,277 @@
+package Repository
+
+import (
+	"backend/Schemas"
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Matches in comments count less towards a post's rank than matches in the
+// post itself
+const commentScoreWeight = 0.5
+
+// At most this many matching comments are considered per search
+const maxCommentMatches = 500
+
+// SearchQuery is a full-text search over posts and their comments
+type SearchQuery struct {
+	Text string
+	// TagIDs restricts results like PostQuery.TagIDs
+	TagIDs []string
+	Limit  int
+}
+
+// SearchHit is a post matching a search, with its rank and the comments that
+// matched
+type SearchHit struct {
+	Post            Schemas.Post
+	Score           float64
+	MatchedComments []Schemas.Comment
+}
+
+// SearchRepository ranks posts by how well they and their comments match a query
+type SearchRepository interface {
+	Search(ctx context.Context, query SearchQuery) ([]SearchHit, error)
+}
+
+// SearchTerms splits text into the lowercase words a search matches on
+func SearchTerms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	seen := make(map[string]bool)
+	for _, word := range words {
+		if len(word) < 2 || seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+// rankHits merges post and comment matches, orders them by score and keeps
+// the best limit
+func rankHits(hits map[primitive.ObjectID]*SearchHit, limit int) []SearchHit {
+	ranked := make([]SearchHit, 0, len(hits))
+	for _, hit := range hits {
+		ranked = append(ranked, *hit)
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Post.ID.Hex() > ranked[j].Post.ID.Hex()
+	})
+
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
+
+// ─── MongoDB ────────────────────────────────────────────────────────────────
+
+type mongoSearchRepository struct{}
+
+func (r *mongoSearchRepository) Search(ctx context.Context, query SearchQuery) ([]SearchHit, error) {
+	limit := normalizeLimit(query.Limit)
+	hits := make(map[primitive.ObjectID]*SearchHit)
+
+	tagFilter := bson.M{}
+	if query.TagIDs != nil {
+		tagFilter = bson.M{"tags": bson.M{"$in": query.TagIDs}}
+	}
+
+	// Posts whose problem matches the text index
+	postFilter := bson.M{"$text": bson.M{"$search": query.Text}}
+	for key, value := range tagFilter {
+		postFilter[key] = value
+	}
+	postOptions := options.Find().
+		SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}}).
+		SetSort(bson.M{"score": bson.M{"$meta": "textScore"}}).
+		SetLimit(int64(limit))
+
+	var scoredPosts []struct {
+		Schemas.Post `bson:",inline"`
+		Score        float64 `bson:"score"`
+	}
+	postsColl, err := collection(PostsCollection)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := postsColl.Find(ctx, postFilter, postOptions)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &scoredPosts); err != nil {
+		return nil, err
+	}
+	for _, scored := range scoredPosts {
+		hits[scored.ID] = &SearchHit{Post: scored.Post, Score: scored.Score}
+	}
+
+	// Comments matching the text index add to the score of their post
+	commentOptions := options.Find().
+		SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}}).
+		SetSort(bson.M{"score": bson.M{"$meta": "textScore"}}).
+		SetLimit(maxCommentMatches)
+
+	var scoredComments []struct {
+		Schemas.Comment `bson:",inline"`
+		Score           float64 `bson:"score"`
+	}
+	commentsColl, err := collection(CommentsCollection)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err = commentsColl.Find(ctx, bson.M{"$text": bson.M{"$search": query.Text}}, commentOptions)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &scoredComments); err != nil {
+		return nil, err
+	}
+
+	// Load the posts of matching comments that did not match themselves
+	var missingPostIDs []primitive.ObjectID
+	for _, scored := range scoredComments {
+		postID, err := primitive.ObjectIDFromHex(scored.PostId)
+		if err != nil {
+			continue
+		}
+		if _, ok := hits[postID]; !ok {
+			missingPostIDs = append(missingPostIDs, postID)
+		}
+	}
+	if len(missingPostIDs) > 0 {
+		missingFilter := bson.M{"_id": bson.M{"$in": missingPostIDs}}
+		for key, value := range tagFilter {
+			missingFilter[key] = value
+		}
+
+		var posts []Schemas.Post
+		cursor, err := postsColl.Find(ctx, missingFilter)
+		if err != nil {
+			return nil, err
+		}
+		if err := cursor.All(ctx, &posts); err != nil {
+			return nil, err
+		}
+		for _, post := range posts {
+			hits[post.ID] = &SearchHit{Post: post}
+		}
+	}
+
+	for _, scored := range scoredComments {
+		postID, err := primitive.ObjectIDFromHex(scored.PostId)
+		if err != nil {
+			continue
+		}
+		// Posts excluded by the tag filter are not in hits
+		if hit, ok := hits[postID]; ok {
+			hit.Score += commentScoreWeight * scored.Score
+			hit.MatchedComments = append(hit.MatchedComments, scored.Comment)
+		}
+	}
+
+	return rankHits(hits, limit), nil
+}
+
+func (r *mongoSearchRepository) ensureIndexes(ctx context.Context) error {
+	postsColl, err := collection(PostsCollection)
+	if err != nil {
+		return err
+	}
+	_, err = postsColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "problem", Value: "text"}},
+	})
+	if err != nil {
+		return err
+	}
+
+	commentsColl, err := collection(CommentsCollection)
+	if err != nil {
+		return err
+	}
+	_, err = commentsColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "description", Value: "text"}},
+	})
+	return err
+}
+
+// ─── In memory ──────────────────────────────────────────────────────────────
+
+// memorySearchRepository scores documents by the number of query terms
+// they contain, as a stand-in for the Mongo text index
+type memorySearchRepository struct {
+	posts    *memoryPostRepository
+	comments *memoryCommentRepository
+}
+
+func (r *memorySearchRepository) Search(ctx context.Context, query SearchQuery) ([]SearchHit, error) {
+	limit := normalizeLimit(query.Limit)
+	terms := SearchTerms(query.Text)
+	hits := make(map[primitive.ObjectID]*SearchHit)
+
+	posts := r.posts.filter(func(post Schemas.Post) bool {
+		return query.TagIDs == nil || containsAny(post.Tags, query.TagIDs)
+	})
+	postsByID := make(map[string]Schemas.Post, len(posts))
+	for _, post := range posts {
+		postsByID[post.ID.Hex()] = post
+		if score := termScore(post.Problem, terms); score > 0 {
+			hits[post.ID] = &SearchHit{Post: post, Score: score}
+		}
+	}
+
+	r.comments.mu.RLock()
+	defer r.comments.mu.RUnlock()
+
+	for _, id := range r.comments.order {
+		comment := r.comments.comments[id]
+		post, ok := postsByID[comment.PostId]
+		if !ok {
+			continue
+		}
+
+		score := termScore(comment.Description, terms)
+		if score == 0 {
+			continue
+		}
+
+		hit, ok := hits[post.ID]
+		if !ok {
+			hit = &SearchHit{Post: post}
+			hits[post.ID] = hit
+		}
+		hit.Score += commentScoreWeight * score
+		hit.MatchedComments = append(hit.MatchedComments, comment)
+	}
+
+	return rankHits(hits, limit), nil
+}
+
+// termScore counts how many words of text are one of terms
+func termScore(text string, terms []string) float64 {
+	var score float64
+	for _, word := range SearchTerms(text) {
+		for _, term := range terms {
+			if word == term {
+				score++
+			}
+		}
+	}
+	return score
+}
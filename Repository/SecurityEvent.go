@@ -0,0 +1,93 @@
+package Repository
+
+import (
+	"backend/Schemas"
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SecurityEventRepository keeps the audit trail of account security events
+type SecurityEventRepository interface {
+	Record(ctx context.Context, event Schemas.SecurityEvent) error
+	// FindByUser returns up to limit of the user's newest events, newest first
+	FindByUser(ctx context.Context, userID primitive.ObjectID, limit int) ([]Schemas.SecurityEvent, error)
+}
+
+// ─── MongoDB ────────────────────────────────────────────────────────────────
+
+type mongoSecurityEventRepository struct{}
+
+func (r *mongoSecurityEventRepository) Record(ctx context.Context, event Schemas.SecurityEvent) error {
+	coll, err := usersDatabaseCollection(SecurityEventsCollection)
+	if err != nil {
+		return err
+	}
+	_, err = coll.InsertOne(ctx, event)
+	return err
+}
+
+func (r *mongoSecurityEventRepository) FindByUser(ctx context.Context, userID primitive.ObjectID, limit int) ([]Schemas.SecurityEvent, error) {
+	findOptions := options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(int64(normalizeLimit(limit)))
+	coll, err := usersDatabaseCollection(SecurityEventsCollection)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := coll.Find(ctx, bson.M{"user_id": userID}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]Schemas.SecurityEvent, 0)
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *mongoSecurityEventRepository) ensureIndexes(ctx context.Context) error {
+	coll, err := usersDatabaseCollection(SecurityEventsCollection)
+	if err != nil {
+		return err
+	}
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+	})
+	return err
+}
+
+// ─── In memory ──────────────────────────────────────────────────────────────
+
+type memorySecurityEventRepository struct {
+	mu     sync.RWMutex
+	events []Schemas.SecurityEvent
+}
+
+func (r *memorySecurityEventRepository) Record(ctx context.Context, event Schemas.SecurityEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if event.ID.IsZero() {
+		event.ID = primitive.NewObjectID()
+	}
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *memorySecurityEventRepository) FindByUser(ctx context.Context, userID primitive.ObjectID, limit int) ([]Schemas.SecurityEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	limit = normalizeLimit(limit)
+	events := make([]Schemas.SecurityEvent, 0)
+	for i := len(r.events) - 1; i >= 0 && len(events) < limit; i-- {
+		if r.events[i].UserID == userID {
+			events = append(events, r.events[i])
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,132 @@
+package Repository
+
+import (
+	"backend/Schemas"
+	"context"
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TagRepository stores tags. Tag IDs are hex ObjectIDs and posts reference
+// tags by these IDs.
+type TagRepository interface {
+	FindByID(ctx context.Context, id string) (Schemas.Tag, error)
+	FindByName(ctx context.Context, name string) (Schemas.Tag, error)
+	FindAll(ctx context.Context) ([]Schemas.Tag, error)
+	Create(ctx context.Context, tag Schemas.Tag) (string, error)
+}
+
+// ─── MongoDB ────────────────────────────────────────────────────────────────
+
+type mongoTagRepository struct{}
+
+func (r *mongoTagRepository) FindByID(ctx context.Context, id string) (Schemas.Tag, error) {
+	var tag Schemas.Tag
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return tag, ErrNotFound
+	}
+
+	coll, err := collection(TagsCollection)
+	if err != nil {
+		return Schemas.Tag{}, err
+	}
+	err = coll.FindOne(ctx, bson.M{"_id": oid}).Decode(&tag)
+	return tag, notFound(err)
+}
+
+func (r *mongoTagRepository) FindByName(ctx context.Context, name string) (Schemas.Tag, error) {
+	var tag Schemas.Tag
+	coll, err := collection(TagsCollection)
+	if err != nil {
+		return Schemas.Tag{}, err
+	}
+	err = coll.FindOne(ctx, bson.M{"name": name}).Decode(&tag)
+	return tag, notFound(err)
+}
+
+func (r *mongoTagRepository) FindAll(ctx context.Context) ([]Schemas.Tag, error) {
+	coll, err := collection(TagsCollection)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := coll.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]Schemas.Tag, 0)
+	if err := cursor.All(ctx, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (r *mongoTagRepository) Create(ctx context.Context, tag Schemas.Tag) (string, error) {
+	coll, err := collection(TagsCollection)
+	if err != nil {
+		return "", err
+	}
+	result, err := coll.InsertOne(ctx, tag)
+	if err != nil {
+		return "", err
+	}
+
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		return id.Hex(), nil
+	}
+	return fmt.Sprint(result.InsertedID), nil
+}
+
+// ─── In memory ──────────────────────────────────────────────────────────────
+
+type memoryTagRepository struct {
+	mu   sync.RWMutex
+	tags []Schemas.Tag
+}
+
+func newMemoryTagRepository() *memoryTagRepository {
+	return &memoryTagRepository{}
+}
+
+func (r *memoryTagRepository) FindByID(ctx context.Context, id string) (Schemas.Tag, error) {
+	return r.findFirst(func(tag Schemas.Tag) bool { return tag.ID == id })
+}
+
+func (r *memoryTagRepository) FindByName(ctx context.Context, name string) (Schemas.Tag, error) {
+	return r.findFirst(func(tag Schemas.Tag) bool { return tag.Name == name })
+}
+
+func (r *memoryTagRepository) findFirst(match func(Schemas.Tag) bool) (Schemas.Tag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, tag := range r.tags {
+		if match(tag) {
+			return tag, nil
+		}
+	}
+	return Schemas.Tag{}, ErrNotFound
+}
+
+func (r *memoryTagRepository) FindAll(ctx context.Context) ([]Schemas.Tag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]Schemas.Tag{}, r.tags...), nil
+}
+
+func (r *memoryTagRepository) Create(ctx context.Context, tag Schemas.Tag) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if tag.ID == "" {
+		tag.ID = primitive.NewObjectID().Hex()
+	}
+	r.tags = append(r.tags, tag)
+	return tag.ID, nil
+}
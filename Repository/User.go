@@ -0,0 +1,398 @@
+package Repository
+
+import (
+	"backend/Schemas"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Names of the unique user indexes, used to tell which field clashed
+const (
+	usernameIndexName = "username_ci_unique"
+	emailIndexName    = "email_ci_unique"
+)
+
+// caseInsensitive makes comparisons ignore case, but not accents, so "Alice"
+// and "alice" are the same username while "José" and "Jose" are not. Queries
+// must use it to hit the indexes.
+var caseInsensitive = &options.Collation{Locale: "en", Strength: 2}
+
+// UserRepository stores registered users
+type UserRepository interface {
+	FindByID(ctx context.Context, id primitive.ObjectID) (Schemas.User, error)
+	// FindByUsername and FindByEmail ignore case
+	FindByUsername(ctx context.Context, username string) (Schemas.User, error)
+	FindByEmail(ctx context.Context, email string) (Schemas.User, error)
+	// Create returns a *DuplicateError naming the field when the username or
+	// email is taken, ignoring case
+	Create(ctx context.Context, user Schemas.User) (primitive.ObjectID, error)
+	// UpdatePassword stores the new hash and bumps the token version, so
+	// tokens issued before the change stop working
+	UpdatePassword(ctx context.Context, id primitive.ObjectID, passwordHash string) error
+	SetRole(ctx context.Context, id primitive.ObjectID, role string) error
+	SetBanned(ctx context.Context, id primitive.ObjectID, banned bool) error
+	// MarkVerified clears the pending email verification
+	MarkVerified(ctx context.Context, id primitive.ObjectID) error
+	CountByRole(ctx context.Context, role string) (int64, error)
+}
+
+// ─── MongoDB ────────────────────────────────────────────────────────────────
+
+type mongoUserRepository struct{}
+
+func (r *mongoUserRepository) FindByID(ctx context.Context, id primitive.ObjectID) (Schemas.User, error) {
+	var user Schemas.User
+	coll, err := usersCollection()
+	if err != nil {
+		return Schemas.User{}, err
+	}
+	err = coll.FindOne(ctx, bson.M{"_id": id}).Decode(&user)
+	return user, notFound(err)
+}
+
+func (r *mongoUserRepository) FindByUsername(ctx context.Context, username string) (Schemas.User, error) {
+	var user Schemas.User
+	coll, err := usersCollection()
+	if err != nil {
+		return Schemas.User{}, err
+	}
+	err = coll.FindOne(ctx, bson.M{"username": username}, options.FindOne().SetCollation(caseInsensitive)).Decode(&user)
+	return user, notFound(err)
+}
+
+func (r *mongoUserRepository) FindByEmail(ctx context.Context, email string) (Schemas.User, error) {
+	var user Schemas.User
+	coll, err := usersCollection()
+	if err != nil {
+		return Schemas.User{}, err
+	}
+	err = coll.FindOne(ctx, bson.M{"email": email}, options.FindOne().SetCollation(caseInsensitive)).Decode(&user)
+	return user, notFound(err)
+}
+
+func (r *mongoUserRepository) Create(ctx context.Context, user Schemas.User) (primitive.ObjectID, error) {
+	coll, err := usersCollection()
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	result, err := coll.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return primitive.NilObjectID, duplicateUserField(err)
+	}
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	id, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return primitive.NilObjectID, errors.New("inserted user ID is not an ObjectID")
+	}
+	return id, nil
+}
+
+// duplicateUserField tells from the violated index which field is taken. Any
+// other clash, e.g. on _id, is a plain ErrDuplicate.
+func duplicateUserField(err error) error {
+	switch {
+	case strings.Contains(err.Error(), emailIndexName):
+		return &DuplicateError{Field: "email"}
+	case strings.Contains(err.Error(), usernameIndexName):
+		return &DuplicateError{Field: "username"}
+	}
+	return fmt.Errorf("%w: %v", ErrDuplicate, err)
+}
+
+func (r *mongoUserRepository) UpdatePassword(ctx context.Context, id primitive.ObjectID, passwordHash string) error {
+	coll, err := usersCollection()
+	if err != nil {
+		return err
+	}
+	result, err := coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{"password": passwordHash},
+		"$inc": bson.M{"token_version": 1},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoUserRepository) SetRole(ctx context.Context, id primitive.ObjectID, role string) error {
+	return r.set(ctx, id, bson.M{"role": role})
+}
+
+func (r *mongoUserRepository) SetBanned(ctx context.Context, id primitive.ObjectID, banned bool) error {
+	return r.set(ctx, id, bson.M{"banned": banned})
+}
+
+func (r *mongoUserRepository) MarkVerified(ctx context.Context, id primitive.ObjectID) error {
+	coll, err := usersCollection()
+	if err != nil {
+		return err
+	}
+	result, err := coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$unset": bson.M{"pending_verification": ""}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoUserRepository) set(ctx context.Context, id primitive.ObjectID, fields bson.M) error {
+	coll, err := usersCollection()
+	if err != nil {
+		return err
+	}
+	result, err := coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": fields})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoUserRepository) CountByRole(ctx context.Context, role string) (int64, error) {
+	coll, err := usersCollection()
+	if err != nil {
+		return 0, err
+	}
+	return coll.CountDocuments(ctx, bson.M{"role": role})
+}
+
+func (r *mongoUserRepository) ensureIndexes(ctx context.Context) error {
+	coll, err := usersCollection()
+	if err != nil {
+		return err
+	}
+	existing, err := coll.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, index := range []struct{ field, name string }{
+		{"username", usernameIndexName},
+		{"email", emailIndexName},
+	} {
+		if hasIndex(existing, index.name) {
+			continue
+		}
+
+		// Accounts from before the index may differ only in case, which
+		// would fail the build. They are reported for an admin to rename.
+		duplicates, err := findCaseDuplicates(ctx, coll, index.field)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("looking for duplicate %ss: %w", index.field, err))
+			continue
+		}
+		if len(duplicates) > 0 {
+			errs = append(errs, &CaseDuplicatesError{Field: index.field, Index: index.name, Duplicates: duplicates})
+			continue
+		}
+
+		_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: index.field, Value: 1}},
+			Options: options.Index().SetName(index.name).SetUnique(true).SetCollation(caseInsensitive),
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("creating %s: %w", index.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func hasIndex(specifications []*mongo.IndexSpecification, name string) bool {
+	for _, specification := range specifications {
+		if specification.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CaseDuplicatesError reports users whose field is equal ignoring case, which
+// keeps its unique index from being built
+type CaseDuplicatesError struct {
+	Field string
+	Index string
+	// Each entry holds the clashing users as "value (id)"
+	Duplicates [][]string
+}
+
+func (e *CaseDuplicatesError) Error() string {
+	groups := make([]string, len(e.Duplicates))
+	for i, users := range e.Duplicates {
+		groups[i] = strings.Join(users, ", ")
+	}
+	return fmt.Sprintf("not creating %s, %d %ss are used by several users ignoring case, rename all but one of each: %s",
+		e.Index, len(e.Duplicates), e.Field, strings.Join(groups, "; "))
+}
+
+// findCaseDuplicates groups the users by field under caseInsensitive and
+// returns the groups with more than one user
+func findCaseDuplicates(ctx context.Context, coll *mongo.Collection, field string) ([][]string, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{field: bson.M{"$type": "string"}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    "$" + field,
+			"values": bson.M{"$push": "$" + field},
+			"ids":    bson.M{"$push": "$_id"},
+		}}},
+		{{Key: "$match", Value: bson.M{"ids.1": bson.M{"$exists": true}}}},
+	}
+	cursor, err := coll.Aggregate(ctx, pipeline, options.Aggregate().SetCollation(caseInsensitive))
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []struct {
+		Values []string             `bson:"values"`
+		IDs    []primitive.ObjectID `bson:"ids"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	duplicates := make([][]string, len(groups))
+	for i, group := range groups {
+		for j, value := range group.Values {
+			duplicates[i] = append(duplicates[i], fmt.Sprintf("%s (%s)", value, group.IDs[j].Hex()))
+		}
+	}
+	return duplicates, nil
+}
+
+// ─── In memory ──────────────────────────────────────────────────────────────
+
+type memoryUserRepository struct {
+	mu    sync.RWMutex
+	users map[primitive.ObjectID]Schemas.User
+}
+
+func newMemoryUserRepository() *memoryUserRepository {
+	return &memoryUserRepository{users: make(map[primitive.ObjectID]Schemas.User)}
+}
+
+func (r *memoryUserRepository) FindByID(ctx context.Context, id primitive.ObjectID) (Schemas.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return Schemas.User{}, ErrNotFound
+	}
+	return user, nil
+}
+
+func (r *memoryUserRepository) FindByUsername(ctx context.Context, username string) (Schemas.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if strings.EqualFold(user.Name, username) {
+			return user, nil
+		}
+	}
+	return Schemas.User{}, ErrNotFound
+}
+
+func (r *memoryUserRepository) FindByEmail(ctx context.Context, email string) (Schemas.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) {
+			return user, nil
+		}
+	}
+	return Schemas.User{}, ErrNotFound
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, user Schemas.User) (primitive.ObjectID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if strings.EqualFold(existing.Name, user.Name) {
+			return primitive.NilObjectID, &DuplicateError{Field: "username"}
+		}
+		if strings.EqualFold(existing.Email, user.Email) {
+			return primitive.NilObjectID, &DuplicateError{Field: "email"}
+		}
+	}
+
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	if _, ok := r.users[user.ID]; ok {
+		return primitive.NilObjectID, ErrDuplicate
+	}
+	r.users[user.ID] = user
+	return user.ID, nil
+}
+
+func (r *memoryUserRepository) UpdatePassword(ctx context.Context, id primitive.ObjectID, passwordHash string) error {
+	return r.update(id, func(user *Schemas.User) {
+		user.Password = passwordHash
+		user.TokenVersion++
+	})
+}
+
+func (r *memoryUserRepository) SetRole(ctx context.Context, id primitive.ObjectID, role string) error {
+	return r.update(id, func(user *Schemas.User) {
+		user.Role = role
+	})
+}
+
+func (r *memoryUserRepository) SetBanned(ctx context.Context, id primitive.ObjectID, banned bool) error {
+	return r.update(id, func(user *Schemas.User) {
+		user.Banned = banned
+	})
+}
+
+func (r *memoryUserRepository) MarkVerified(ctx context.Context, id primitive.ObjectID) error {
+	return r.update(id, func(user *Schemas.User) {
+		user.PendingVerification = false
+	})
+}
+
+func (r *memoryUserRepository) CountByRole(ctx context.Context, role string) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, user := range r.users {
+		if user.Role == role {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryUserRepository) update(id primitive.ObjectID, change func(*Schemas.User)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	change(&user)
+	r.users[id] = user
+	return nil
+}
@@ -0,0 +1,141 @@
+package Repository
+
+import (
+	"backend/Schemas"
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// How long used and expired tokens are kept before the database drops them
+const userTokenRetention = 7 * 24 * time.Hour
+
+// UserTokenRepository stores hashed one-time tokens such as password resets
+type UserTokenRepository interface {
+	Create(ctx context.Context, token Schemas.UserToken) error
+	// Consume marks the unused, unexpired token with tokenHash as used and
+	// returns it. Any other token gives ErrNotFound, so a token works once.
+	Consume(ctx context.Context, purpose string, tokenHash string, now time.Time) (Schemas.UserToken, error)
+	// DeleteByUser removes the user's tokens for purpose, e.g. older reset
+	// links when a new one is requested
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID, purpose string) error
+}
+
+// ─── MongoDB ────────────────────────────────────────────────────────────────
+
+type mongoUserTokenRepository struct{}
+
+func (r *mongoUserTokenRepository) Create(ctx context.Context, token Schemas.UserToken) error {
+	coll, err := usersDatabaseCollection(UserTokensCollection)
+	if err != nil {
+		return err
+	}
+	_, err = coll.InsertOne(ctx, token)
+	return err
+}
+
+func (r *mongoUserTokenRepository) Consume(ctx context.Context, purpose string, tokenHash string, now time.Time) (Schemas.UserToken, error) {
+	filter := bson.M{
+		"purpose":    purpose,
+		"token_hash": tokenHash,
+		"used_at":    bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": now},
+	}
+	update := bson.M{"$set": bson.M{"used_at": now}}
+
+	var token Schemas.UserToken
+	coll, err := usersDatabaseCollection(UserTokensCollection)
+	if err != nil {
+		return Schemas.UserToken{}, err
+	}
+	err = coll.
+		FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).
+		Decode(&token)
+	return token, notFound(err)
+}
+
+func (r *mongoUserTokenRepository) DeleteByUser(ctx context.Context, userID primitive.ObjectID, purpose string) error {
+	coll, err := usersDatabaseCollection(UserTokensCollection)
+	if err != nil {
+		return err
+	}
+	_, err = coll.DeleteMany(ctx, bson.M{"user_id": userID, "purpose": purpose})
+	return err
+}
+
+func (r *mongoUserTokenRepository) ensureIndexes(ctx context.Context) error {
+	coll, err := usersDatabaseCollection(UserTokensCollection)
+	if err != nil {
+		return err
+	}
+	_, err = coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "purpose", Value: 1}},
+		},
+		{
+			// Let MongoDB clean up old tokens
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(userTokenRetention.Seconds())),
+		},
+	})
+	return err
+}
+
+// ─── In memory ──────────────────────────────────────────────────────────────
+
+type memoryUserTokenRepository struct {
+	mu     sync.Mutex
+	tokens map[string]Schemas.UserToken
+}
+
+func newMemoryUserTokenRepository() *memoryUserTokenRepository {
+	return &memoryUserTokenRepository{tokens: make(map[string]Schemas.UserToken)}
+}
+
+func (r *memoryUserTokenRepository) Create(ctx context.Context, token Schemas.UserToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.tokens[token.TokenHash]; exists {
+		return ErrDuplicate
+	}
+	if token.ID.IsZero() {
+		token.ID = primitive.NewObjectID()
+	}
+	r.tokens[token.TokenHash] = token
+	return nil
+}
+
+func (r *memoryUserTokenRepository) Consume(ctx context.Context, purpose string, tokenHash string, now time.Time) (Schemas.UserToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[tokenHash]
+	if !ok || token.Purpose != purpose || token.UsedAt != nil || !token.ExpiresAt.After(now) {
+		return Schemas.UserToken{}, ErrNotFound
+	}
+	token.UsedAt = &now
+	r.tokens[tokenHash] = token
+	return token, nil
+}
+
+func (r *memoryUserTokenRepository) DeleteByUser(ctx context.Context, userID primitive.ObjectID, purpose string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for hash, token := range r.tokens {
+		if token.UserID == userID && token.Purpose == purpose {
+			delete(r.tokens, hash)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package Schemas
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ChatRoom struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name      string             `json:"name" bson:"name"`
+	CreatedBy string             `json:"created_by" bson:"created_by"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type ChatMessage struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Room     string             `json:"room" bson:"room"`
+	UserID   primitive.ObjectID `json:"user_id" bson:"user_id,omitempty"` // The authenticated sender
+	Username string             `json:"username" bson:"username"`
+	Content  string             `json:"content" bson:"content"`
+	Hidden   bool               `json:"hidden" bson:"hidden"` // Replaced by AI moderation
+	SentAt   time.Time          `json:"sent_at" bson:"sent_at"`
+}
@@ -1,6 +1,10 @@
 package Schemas
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type Comment struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty"`
@@ -8,5 +12,7 @@ type Comment struct {
 	Username    string             `json:"username" bson:"username"`
 	Description string             `json:"description" bson:"description"`
 	Date        string             `json:"date" bson:"date"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at,omitempty"` // Zero for comments stored before it was added
 	LikeCount   int                `json:"likeCount" bson:"likeCount"`
+	LikedByMe   bool               `json:"liked_by_me" bson:"-"` // Filled in per request for the authenticated user
 }
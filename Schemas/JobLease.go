@@ -0,0 +1,11 @@
+package Schemas
+
+import "time"
+
+// JobLease is held by the instance running a job, so instances sharing a
+// database never run the same job at once
+type JobLease struct {
+	Job       string    `json:"job" bson:"_id"`
+	Holder    string    `json:"holder" bson:"holder"` // Identifies the instance
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+}
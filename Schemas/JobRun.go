@@ -0,0 +1,19 @@
+package Schemas
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type JobRun struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Job        string             `json:"job" bson:"job"`
+	Trigger    string             `json:"trigger" bson:"trigger"` // "schedule" or "manual"
+	StartedAt  time.Time          `json:"started_at" bson:"started_at"`
+	FinishedAt time.Time          `json:"finished_at" bson:"finished_at"`
+	DurationMs int64              `json:"duration_ms" bson:"duration_ms"`
+	Success    bool               `json:"success" bson:"success"`
+	Result     string             `json:"result,omitempty" bson:"result,omitempty"`
+	Error      string             `json:"error,omitempty" bson:"error,omitempty"`
+}
@@ -0,0 +1,23 @@
+package Schemas
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kinds of content that can be liked
+const (
+	LikeTargetPost    = "post"
+	LikeTargetComment = "comment"
+)
+
+// Like records that a user likes a post or comment. A user likes a target
+// at most once.
+type Like struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID     primitive.ObjectID `json:"user_id" bson:"user_id"`
+	TargetType string             `json:"target_type" bson:"target_type"`
+	TargetID   primitive.ObjectID `json:"target_id" bson:"target_id"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+}
@@ -0,0 +1,10 @@
+package Schemas
+
+import "time"
+
+// LoginAttempts counts recent failed logins for one username or IP address
+type LoginAttempts struct {
+	Key         string    `json:"key" bson:"_id"` // "user:<name>" or "ip:<address>"
+	Failures    int       `json:"failures" bson:"failures"`
+	LastFailure time.Time `json:"last_failure" bson:"last_failure"`
+}
@@ -1,14 +1,25 @@
 package Schemas
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type Post struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty"`
-	Username  string             `json:"username" bson:"username"`
-	Problem   string             `json:"problem" bson:"problem"`
-	Date      string             `json:"date" bson:"date"`
-	LikeCount int                `json:"likeCount" bson:"likeCount"`
-	Locked    bool               `json:"locked" bson:"locked"`
-	Comments  []Comment          `json:"comments"`
-	Tags      []string           `json:"tags" bson:"tags"` // New field for tags
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	Username     string             `json:"username" bson:"username"`
+	Problem      string             `json:"problem" bson:"problem"`
+	Date         string             `json:"date" bson:"date"`
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at,omitempty"` // Zero for posts stored before it was added
+	LikeCount    int                `json:"likeCount" bson:"likeCount"`
+	LikedByMe    bool               `json:"liked_by_me" bson:"-"`             // Filled in per request for the authenticated user
+	CommentCount int                `json:"commentCount" bson:"commentCount"` // Kept in sync by the comment handlers
+	Locked       bool               `json:"locked" bson:"locked"`
+	LockReason   string             `json:"lock_reason,omitempty" bson:"lock_reason,omitempty"`
+	LockedBy     string             `json:"locked_by,omitempty" bson:"locked_by,omitempty"`
+	LockedAt     *time.Time         `json:"locked_at,omitempty" bson:"locked_at,omitempty"`
+	Pinned       bool               `json:"pinned" bson:"pinned,omitempty"` // Pinned posts are never locked automatically
+	Comments     []Comment          `json:"comments"`
+	Tags         []string           `json:"tags" bson:"tags"` // New field for tags
 }
@@ -0,0 +1,12 @@
+package Schemas
+
+import "time"
+
+// RateLimitBucket is the state of one token bucket of the rate limiter
+type RateLimitBucket struct {
+	Key       string    `json:"key" bson:"_id"` // "<group>:user:<id>" or "<group>:ip:<address>"
+	Tokens    float64   `json:"tokens" bson:"tokens"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+	// Allowed tells whether the last take got a token
+	Allowed bool `json:"allowed" bson:"allowed"`
+}
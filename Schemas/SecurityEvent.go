@@ -0,0 +1,29 @@
+package Schemas
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Types of security events
+const (
+	SecurityEventPasswordChanged        = "password_changed"
+	SecurityEventPasswordChangeFailed   = "password_change_failed"
+	SecurityEventPasswordResetRequested = "password_reset_requested"
+	SecurityEventEmailVerified          = "email_verified"
+	SecurityEventPasswordReset          = "password_reset"
+	SecurityEventLoginFailed            = "login_failed"
+	SecurityEventAccountLocked          = "account_locked"
+)
+
+// SecurityEvent is an audit record of a security relevant action on an account
+type SecurityEvent struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Username  string             `json:"username" bson:"username"`
+	Type      string             `json:"type" bson:"type"`
+	IP        string             `json:"ip" bson:"ip"`
+	UserAgent string             `json:"user_agent" bson:"user_agent"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
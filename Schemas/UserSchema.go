@@ -3,8 +3,19 @@ package Schemas
 import "go.mongodb.org/mongo-driver/bson/primitive"
 
 type User struct {
-	ID       primitive.ObjectID `bson:"_id,omitempty"`
-	Name     string             `json:"username" bson:"username"`
-	Email    string             `json:"email" bson:"email"`
-	Password string             `json:"password" bson:"password"`
+	ID                  primitive.ObjectID `bson:"_id,omitempty"`
+	Name                string             `json:"username" bson:"username"`
+	Email               string             `json:"email" bson:"email"`
+	Password            string             `json:"password" bson:"password"`
+	Role                string             `json:"role" bson:"role,omitempty"` // Empty for accounts created before roles, treated as RoleUser
+	Banned              bool               `json:"banned" bson:"banned,omitempty"`
+	PendingVerification bool               `json:"-" bson:"pending_verification,omitempty"` // Set until the email address is confirmed
+	TokenVersion        int                `json:"-" bson:"token_version,omitempty"`        // Bumped to revoke every issued token
 }
+
+// Roles a user can have, from least to most privileged
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
@@ -0,0 +1,72 @@
+// Command bootstrap-admin promotes an existing user to admin. Admins can
+// promote everyone else through PUT /admin/users/:username/role, so this is
+// only needed once per deployment:
+//
+//	go run ./cmd/bootstrap-admin -username alice
+//
+// It refuses to run when an admin already exists unless -force is given.
+package main
+
+import (
+	"backend/Config"
+	"backend/Mongo"
+	"backend/Repository"
+	"backend/Schemas"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+func main() {
+	username := flag.String("username", "", "user to promote to admin")
+	force := flag.Bool("force", false, "promote even when an admin already exists")
+	flag.Parse()
+
+	if *username == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Settings come from the environment, .env and CONFIG_FILE like the server's
+	config, err := Config.Load(nil)
+	if err != nil {
+		log.Fatalf("bootstrap-admin: %v", err)
+	}
+	Repository.Configure(config.Storage)
+	Mongo.Configure(config.Storage.MongoURI)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := promote(ctx, *username, *force); err != nil {
+		log.Fatalf("bootstrap-admin: %v", err)
+	}
+	fmt.Printf("%s is now an admin\n", *username)
+}
+
+func promote(ctx context.Context, username string, force bool) error {
+	admins, err := Repository.Users().CountByRole(ctx, Schemas.RoleAdmin)
+	if err != nil {
+		return fmt.Errorf("could not count admins: %w", err)
+	}
+	if admins > 0 && !force {
+		return fmt.Errorf("%d admin(s) already exist, use -force to add another", admins)
+	}
+
+	user, err := Repository.Users().FindByUsername(ctx, username)
+	if errors.Is(err, Repository.ErrNotFound) {
+		return fmt.Errorf("user %q does not exist, register it first", username)
+	}
+	if err != nil {
+		return fmt.Errorf("could not load user: %w", err)
+	}
+
+	if err := Repository.Users().SetRole(ctx, user.ID, Schemas.RoleAdmin); err != nil {
+		return fmt.Errorf("could not update user: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+// Command chatload measures chat throughput against a running server. It
+// connects many WebSocket clients to one room, lets a few of them send
+// messages and reports how many broadcasts arrived and how fast.
+//
+// Run the server with AI_PROVIDER=fake so moderation does not call a paid
+// API, then for example:
+//
+//	go run ./cmd/chatload -clients 500 -senders 20 -messages 50 -token <access token>
+//
+// Senders connect with the token, so they all send as its user and share the
+// chat rate limit of that user.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "server address")
+	room := flag.String("room", "loadtest", "room to join")
+	token := flag.String("token", "", "access token used to create the room and to send messages")
+	clients := flag.Int("clients", 200, "number of connected clients")
+	senders := flag.Int("senders", 10, "number of clients that send messages")
+	messages := flag.Int("messages", 50, "messages sent by every sender")
+	interval := flag.Duration("interval", 10*time.Millisecond, "pause between messages of one sender")
+	drain := flag.Duration("drain", 5*time.Second, "how long to wait for broadcasts after the last send")
+	flag.Parse()
+
+	if *senders > *clients {
+		*senders = *clients
+	}
+
+	if *token == "" {
+		*senders = 0
+		log.Printf("no -token given, clients only listen")
+	} else {
+		createRoom(*addr, *room, *token)
+	}
+
+	runID := strconv.FormatInt(time.Now().UnixNano(), 36)
+	listenURL := url.URL{Scheme: "ws", Host: *addr, Path: "/ws", RawQuery: url.Values{"room": {*room}}.Encode()}
+	sendURL := listenURL
+	sendURL.RawQuery = url.Values{"room": {*room}, "token": {*token}}.Encode()
+
+	var (
+		delivered atomic.Int64
+		latencyMu sync.Mutex
+		latencies []time.Duration
+		readers   sync.WaitGroup
+	)
+
+	conns := make([]*websocket.Conn, 0, *clients)
+	for i := 0; i < *clients; i++ {
+		wsURL := listenURL
+		if i < *senders {
+			wsURL = sendURL
+		}
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL.String(), nil)
+		if err != nil {
+			log.Fatalf("client %d could not connect: %v", i, err)
+		}
+		conns = append(conns, conn)
+
+		readers.Add(1)
+		go func(conn *websocket.Conn) {
+			defer readers.Done()
+			for {
+				var msg struct {
+					Content string `json:"content"`
+				}
+				if err := conn.ReadJSON(&msg); err != nil {
+					return
+				}
+
+				sentAt, ok := parseProbe(msg.Content, runID)
+				if !ok {
+					// Replayed history or someone else's message
+					continue
+				}
+				delivered.Add(1)
+
+				latencyMu.Lock()
+				latencies = append(latencies, time.Since(sentAt))
+				latencyMu.Unlock()
+			}
+		}(conn)
+	}
+	log.Printf("connected %d clients to room %q", len(conns), *room)
+
+	start := time.Now()
+	var writers sync.WaitGroup
+	for s := 0; s < *senders; s++ {
+		writers.Add(1)
+		go func(sender int, conn *websocket.Conn) {
+			defer writers.Done()
+			for m := 0; m < *messages; m++ {
+				content := fmt.Sprintf("load:%s:%d:%d:%d", runID, sender, m, time.Now().UnixNano())
+				if err := conn.WriteJSON(map[string]string{"content": content}); err != nil {
+					log.Printf("sender %d stopped: %v", sender, err)
+					return
+				}
+				time.Sleep(*interval)
+			}
+		}(s, conns[s])
+	}
+	writers.Wait()
+	sendDuration := time.Since(start)
+
+	time.Sleep(*drain)
+	for _, conn := range conns {
+		conn.Close()
+	}
+	readers.Wait()
+	totalDuration := time.Since(start)
+
+	expected := int64(*senders) * int64(*messages) * int64(*clients)
+	got := delivered.Load()
+	fmt.Printf("sent:        %d messages in %v\n", *senders**messages, sendDuration.Round(time.Millisecond))
+	if expected > 0 {
+		fmt.Printf("delivered:   %d of %d broadcasts (%.1f%%)\n", got, expected, 100*float64(got)/float64(expected))
+	}
+	fmt.Printf("throughput:  %.0f deliveries/s\n", float64(got)/totalDuration.Seconds())
+
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		fmt.Printf("latency:     p50 %v  p95 %v  p99 %v  max %v\n",
+			percentile(latencies, 0.50), percentile(latencies, 0.95), percentile(latencies, 0.99), latencies[len(latencies)-1])
+	}
+}
+
+// createRoom creates the room, ignoring the conflict when it already exists
+func createRoom(addr string, room string, token string) {
+	body, _ := json.Marshal(map[string]string{"room_name": room})
+	req, err := http.NewRequest(http.MethodPost, "http://"+addr+"/create_room", bytes.NewReader(body))
+	if err != nil {
+		log.Fatalf("could not build create_room request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Fatalf("could not create room: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusConflict {
+		log.Fatalf("could not create room: status %d", resp.StatusCode)
+	}
+}
+
+// parseProbe extracts the send time from a message sent by this run
+func parseProbe(content string, runID string) (time.Time, bool) {
+	parts := strings.Split(content, ":")
+	if len(parts) != 5 || parts[0] != "load" || parts[1] != runID {
+		return time.Time{}, false
+	}
+
+	nanos, err := strconv.ParseInt(parts[4], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, nanos), true
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	index := int(float64(len(sorted)-1) * p)
+	return sorted[index].Round(time.Microsecond)
+}
@@ -3,109 +3,187 @@ package cronjobs
 import (
 	"context"
 	"fmt"
-	"log"
 	"time"
 
-	"backend/Mongo"
+	"backend/Config"
+	"backend/Logging"
+	"backend/Repository"
 	"backend/Schemas"
 
-	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func GetAllCommentsForPost(postId string) (comments []Schemas.Comment, err error) {
-	comments = make([]Schemas.Comment, 0)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// Username of the automatic answer every new post receives
+const aiUsername = "AI"
+
+// Recorded as the locker of posts locked by the job
+const lockedBySystem = "lock_old_posts"
+
+// LockPolicy decides which posts LockOldPosts locks
+type LockPolicy struct {
+	// Posts without activity (creation or a comment) for this long are locked
+	InactivityWindow time.Duration
+	// Posts younger than this are never locked
+	MinPostAge time.Duration
+	// Leave pinned posts alone
+	ExemptPinned bool
+	// Leave posts nobody but the AI has answered alone
+	ExemptUnanswered bool
+	// Only report what would be locked
+	DryRun bool
+}
 
-	cursor, err := Mongo.GetCollection("melje_district").Find(ctx, bson.M{"post_id": postId})
-	if err != nil {
-		return
+// LockPolicyFromConfig converts the configured policy
+func LockPolicyFromConfig(cfg Config.LockOldPosts) LockPolicy {
+	return LockPolicy{
+		InactivityWindow: time.Duration(cfg.InactivityDays) * 24 * time.Hour,
+		MinPostAge:       time.Duration(cfg.MinPostAgeDays) * 24 * time.Hour,
+		ExemptPinned:     cfg.ExemptPinned,
+		ExemptUnanswered: cfg.ExemptUnanswered,
+		DryRun:           cfg.DryRun,
 	}
-	defer cursor.Close(ctx)
+}
 
-	for cursor.Next(ctx) {
-		var comment Schemas.Comment
-		err := cursor.Decode(&comment)
-		if err == nil {
-			comments = append(comments, comment)
-		}
+// lockPolicy is the policy LockOldPosts applies, set by RegisterDefaultJobs
+var lockPolicy = LockPolicyFromConfig(Config.Default().Jobs.LockOldPosts)
+
+// CurrentLockPolicy returns the policy LockOldPosts applies
+func CurrentLockPolicy() LockPolicy {
+	return lockPolicy
+}
+
+// LockCandidate is a post the policy would lock
+type LockCandidate struct {
+	PostID       primitive.ObjectID `json:"post_id"`
+	Username     string             `json:"username"`
+	CreatedAt    time.Time          `json:"created_at"`
+	LastActivity time.Time          `json:"last_activity"`
+}
+
+// postCreatedAt prefers the stored timestamp and falls back to the ObjectID,
+// which records when older posts were inserted
+func postCreatedAt(post Schemas.Post) time.Time {
+	if !post.CreatedAt.IsZero() {
+		return post.CreatedAt
+	}
+	if !post.ID.IsZero() {
+		return post.ID.Timestamp()
+	}
+	return parseLegacyDate(post.Date)
+}
+
+func commentCreatedAt(comment Schemas.Comment) time.Time {
+	if !comment.CreatedAt.IsZero() {
+		return comment.CreatedAt
+	}
+	if !comment.ID.IsZero() {
+		return comment.ID.Timestamp()
 	}
-	return
+	return parseLegacyDate(comment.Date)
 }
 
-func LockOldPosts() {
-	// Context for MongoDB operations
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// parseLegacyDate parses the "2006-01-02" dates the handlers have always
+// stored, returning the zero time when it cannot
+func parseLegacyDate(date string) time.Time {
+	parsed, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return time.Time{}
+	}
+	return parsed
+}
 
+// FindLockCandidates returns the unlocked posts policy would lock at now
+func FindLockCandidates(ctx context.Context, policy LockPolicy, now time.Time) ([]LockCandidate, int, error) {
 	// Fetch posts where locked is false or null
-	query := bson.M{
-		"$or": []bson.M{
-			{"locked": bson.M{"$eq": false}},
-			{"locked": bson.M{"$eq": nil}},
-		},
+	unlocked, err := Repository.Posts().FindUnlocked(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error fetching posts: %w", err)
+	}
+
+	// Fetch the comments of every post in one batched query
+	postIDs := make([]string, len(unlocked))
+	for i, post := range unlocked {
+		postIDs[i] = post.ID.Hex()
 	}
 
-	cursor, err := Mongo.GetCollection("studenci_district").Find(ctx, query)
+	commentsByPost, err := Repository.Comments().FindByPostIDs(ctx, postIDs)
 	if err != nil {
-		log.Fatalf("Error fetching posts: %v", err)
+		return nil, 0, fmt.Errorf("error fetching comments: %w", err)
 	}
-	defer cursor.Close(ctx)
 
-	var posts []Schemas.Post
-	for cursor.Next(ctx) {
-		var post Schemas.Post
-		if err := cursor.Decode(&post); err != nil {
-			log.Printf("Error decoding post: %v", err)
+	var candidates []LockCandidate
+	for _, post := range unlocked {
+		if policy.ExemptPinned && post.Pinned {
 			continue
 		}
 
-		// Fetch comments for each post
-		comments, err := GetAllCommentsForPost(post.ID.Hex())
-		if err != nil {
-			log.Printf("Error fetching comments for post ID %s: %v", post.ID.Hex(), err)
+		createdAt := postCreatedAt(post)
+		if createdAt.IsZero() {
+			Logging.FromContext(ctx).Warn("Skipping post, its creation time is unknown", "post_id", post.ID.Hex())
+			continue
+		}
+		if now.Sub(createdAt) < policy.MinPostAge {
 			continue
 		}
 
-		post.Comments = comments
-		posts = append(posts, post)
-	}
+		// The post itself counts as activity, so posts without comments are
+		// measured from their creation
+		lastActivity := createdAt
+		answered := false
+		for _, comment := range commentsByPost[post.ID.Hex()] {
+			if comment.Username != aiUsername {
+				answered = true
+			}
+			if commentDate := commentCreatedAt(comment); commentDate.After(lastActivity) {
+				lastActivity = commentDate
+			}
+		}
 
-	if err := cursor.Err(); err != nil {
-		log.Fatalf("Cursor error: %v", err)
+		if policy.ExemptUnanswered && !answered {
+			continue
+		}
+		if now.Sub(lastActivity) < policy.InactivityWindow {
+			continue
+		}
+
+		candidates = append(candidates, LockCandidate{
+			PostID:       post.ID,
+			Username:     post.Username,
+			CreatedAt:    createdAt,
+			LastActivity: lastActivity,
+		})
 	}
 
-	// Filter posts based on comment date and update if necessary
-	sevenDaysAgo := time.Now().AddDate(0, 0, -7)
-	layout := "2006-01-02T15:04:05Z07:00"
-
-	for _, post := range posts {
-		var newestComment time.Time
-		for _, comment := range post.Comments {
-			commentDate, err := time.Parse(layout, comment.Date)
-			if err != nil {
-				log.Printf("Error parsing date for comment in post ID %s: %v", post.ID.Hex(), err)
-				continue
-			}
+	return candidates, len(unlocked), nil
+}
 
-			if commentDate.After(newestComment) {
-				newestComment = commentDate
-			}
-		}
+// LockOldPosts locks the posts selected by the configured policy
+func LockOldPosts(ctx context.Context) (string, error) {
+	policy := CurrentLockPolicy()
 
-		// Check if the newest comment is older than 7 days
-		if newestComment.Before(sevenDaysAgo) {
-			// Update the `locked` field of the post to true
-			update := bson.M{"$set": bson.M{"locked": true}}
-			_, err := Mongo.GetCollection("studenci_district").UpdateOne(ctx, bson.M{"_id": post.ID}, update)
-			if err != nil {
-				log.Printf("Error updating post ID %s: %v", post.ID.Hex(), err)
-				continue
-			}
+	candidates, checked, err := FindLockCandidates(ctx, policy, time.Now())
+	if err != nil {
+		return "", err
+	}
 
-			fmt.Printf("Post ID: %s locked as the newest comment is older than 7 days\n", post.ID.Hex())
+	if policy.DryRun {
+		for _, candidate := range candidates {
+			Logging.FromContext(ctx).Info("Dry run, would lock post", "post_id", candidate.PostID.Hex(), "last_activity", candidate.LastActivity)
 		}
+		return fmt.Sprintf("dry run, would lock %d of %d unlocked posts", len(candidates), checked), nil
+	}
+
+	locked := 0
+	reason := fmt.Sprintf("No activity for %d days", int(policy.InactivityWindow.Hours()/24))
+	for _, candidate := range candidates {
+		if _, err := Repository.Posts().Lock(ctx, candidate.PostID, reason, lockedBySystem, time.Now().UTC()); err != nil {
+			Logging.FromContext(ctx).Error("Error locking post", "post_id", candidate.PostID.Hex(), "error", err)
+			continue
+		}
+
+		Logging.FromContext(ctx).Info("Post locked", "post_id", candidate.PostID.Hex(), "last_activity", candidate.LastActivity)
+		locked++
 	}
 
-	fmt.Println("Job completed!")
+	return fmt.Sprintf("locked %d of %d unlocked posts", locked, checked), nil
 }
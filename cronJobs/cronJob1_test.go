@@ -0,0 +1,159 @@
+package cronjobs
+
+import (
+	"backend/Repository"
+	"backend/Schemas"
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+const day = 24 * time.Hour
+
+// seedPosts stores posts whose problem is their name, aged relative to now,
+// and returns their names by ID hex
+func seedPosts(t *testing.T, now time.Time) map[string]string {
+	t.Helper()
+	ctx := context.Background()
+	Repository.Use(Repository.NewMemoryRepositories())
+
+	type comment struct {
+		username string
+		age      time.Duration
+	}
+	seeds := []struct {
+		name     string
+		age      time.Duration
+		comments []comment
+		pinned   bool
+		locked   bool
+	}{
+		{name: "stale", age: 30 * day},
+		{name: "young", age: 3 * day},
+		{name: "active", age: 30 * day, comments: []comment{{"bob", 2 * day}}},
+		{name: "stale answered", age: 30 * day, comments: []comment{{"bob", 20 * day}}},
+		{name: "ai only", age: 30 * day, comments: []comment{{aiUsername, 20 * day}}},
+		{name: "pinned", age: 30 * day, pinned: true},
+		{name: "locked", age: 30 * day, locked: true},
+	}
+
+	names := map[string]string{}
+	for _, seed := range seeds {
+		id, err := Repository.Posts().Create(ctx, Schemas.Post{Problem: seed.name, Username: "alice", CreatedAt: now.Add(-seed.age)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		names[id.Hex()] = seed.name
+
+		for _, c := range seed.comments {
+			if _, err := Repository.Comments().Create(ctx, Schemas.Comment{PostId: id.Hex(), Username: c.username, CreatedAt: now.Add(-c.age)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if seed.pinned {
+			if _, err := Repository.Posts().SetPinned(ctx, id, true); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if seed.locked {
+			if _, err := Repository.Posts().Lock(ctx, id, "", "mod", now); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return names
+}
+
+func TestFindLockCandidates(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	base := LockPolicy{InactivityWindow: 7 * day, MinPostAge: 7 * day}
+
+	tests := []struct {
+		name   string
+		change func(policy *LockPolicy)
+		want   []string
+	}{
+		{
+			name: "default windows",
+			want: []string{"ai only", "pinned", "stale", "stale answered"},
+		},
+		{
+			name:   "exempt pinned",
+			change: func(policy *LockPolicy) { policy.ExemptPinned = true },
+			want:   []string{"ai only", "stale", "stale answered"},
+		},
+		{
+			name:   "exempt unanswered",
+			change: func(policy *LockPolicy) { policy.ExemptUnanswered = true },
+			want:   []string{"stale answered"},
+		},
+		{
+			name:   "longer inactivity window",
+			change: func(policy *LockPolicy) { policy.InactivityWindow = 25 * day },
+			want:   []string{"pinned", "stale"},
+		},
+		{
+			name:   "older minimum age",
+			change: func(policy *LockPolicy) { policy.MinPostAge = 60 * day },
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := seedPosts(t, now)
+			policy := base
+			if tt.change != nil {
+				tt.change(&policy)
+			}
+
+			candidates, checked, err := FindLockCandidates(context.Background(), policy, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if checked != len(names)-1 {
+				t.Errorf("checked %d posts, want %d unlocked", checked, len(names)-1)
+			}
+
+			var got []string
+			for _, candidate := range candidates {
+				got = append(got, names[candidate.PostID.Hex()])
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("candidates = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLockOldPostsDryRun(t *testing.T) {
+	defer func(previous LockPolicy) { lockPolicy = previous }(lockPolicy)
+
+	tests := []struct {
+		dryRun     bool
+		wantLocked int
+	}{
+		{dryRun: true, wantLocked: 1},
+		{dryRun: false, wantLocked: 4},
+	}
+
+	for _, tt := range tests {
+		seedPosts(t, time.Now())
+		lockPolicy = LockPolicy{InactivityWindow: 7 * day, MinPostAge: 7 * day, ExemptPinned: true, DryRun: tt.dryRun}
+
+		if _, err := LockOldPosts(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		unlocked, err := Repository.Posts().FindUnlocked(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if locked := 7 - len(unlocked); locked != tt.wantLocked {
+			t.Errorf("dry run %v: %d posts locked, want %d", tt.dryRun, locked, tt.wantLocked)
+		}
+	}
+}
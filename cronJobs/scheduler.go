@@ -0,0 +1,294 @@
+package cronjobs
+
+import (
+	"backend/Config"
+	"backend/Logging"
+	"backend/Metrics"
+	"backend/Repository"
+	"backend/Schemas"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Longest a single job run may take before its context is cancelled
+const jobTimeout = 5 * time.Minute
+
+// leaseMargin keeps a lease a little longer than the run may take, so it
+// does not expire while the run records its result
+const leaseMargin = time.Minute
+
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+var (
+	ErrUnknownJob = errors.New("unknown job")
+	ErrJobRunning = errors.New("job is already running")
+)
+
+// Job is a unit of background work. Run returns a short human readable
+// summary of what it did.
+type Job struct {
+	Name string
+	// Schedule is a standard 5 field cron expression, e.g. "0 3 * * *"
+	Schedule string
+	Run      func(ctx context.Context) (string, error)
+}
+
+// JobStatus describes a registered job for the admin endpoints
+type JobStatus struct {
+	Name     string          `json:"name"`
+	Schedule string          `json:"schedule"`
+	Running  bool            `json:"running"`
+	NextRun  *time.Time      `json:"next_run,omitempty"`
+	LastRun  *Schemas.JobRun `json:"last_run,omitempty"`
+}
+
+type registeredJob struct {
+	Job
+	entryID cron.EntryID
+	running bool
+}
+
+// Scheduler runs registered jobs on their cron schedule. A job never runs
+// twice at the same time, not even on different instances: a run first
+// takes the job's lease in the database, a run that would overlap is
+// skipped.
+type Scheduler struct {
+	mu     sync.Mutex
+	cron   *cron.Cron
+	jobs   map[string]*registeredJob
+	holder string         // Identifies this instance as lease holder
+	manual sync.WaitGroup // Manual runs in progress
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		cron:   cron.New(),
+		jobs:   make(map[string]*registeredJob),
+		holder: leaseHolder(),
+	}
+}
+
+// leaseHolder names this process, unique even for several processes on one
+// host
+func leaseHolder() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s/%d/%s", host, os.Getpid(), primitive.NewObjectID().Hex())
+}
+
+// Default is the scheduler started from main
+var Default = NewScheduler()
+
+// Register adds job to the scheduler. A schedule of "off" disables scheduled
+// runs while keeping manual runs possible.
+func (s *Scheduler) Register(job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[job.Name]; exists {
+		return fmt.Errorf("job %s is already registered", job.Name)
+	}
+
+	registered := &registeredJob{Job: job}
+	if job.Schedule != "off" {
+		entryID, err := s.cron.AddFunc(job.Schedule, func() {
+			run, err := s.begin(job.Name, TriggerSchedule)
+			if errors.Is(err, ErrJobRunning) {
+				slog.Warn("Skipping scheduled run, the previous run is still going", "job", job.Name)
+				return
+			}
+			if err != nil {
+				slog.Error("Could not start scheduled run", "job", job.Name, "error", err)
+				return
+			}
+			s.execute(run)
+		})
+		if err != nil {
+			return fmt.Errorf("invalid schedule %q for job %s: %v", job.Schedule, job.Name, err)
+		}
+		registered.entryID = entryID
+	}
+
+	s.jobs[job.Name] = registered
+	return nil
+}
+
+// Start begins running jobs on their schedules
+func (s *Scheduler) Start() {
+	s.cron.Start()
+}
+
+// Stop prevents new runs and returns a context that is done once the runs
+// in progress, scheduled or manual, have finished
+func (s *Scheduler) Stop() context.Context {
+	scheduled := s.cron.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-scheduled.Done()
+		s.manual.Wait()
+		cancel()
+	}()
+	return ctx
+}
+
+// RunNow starts the named job in the background and returns its run, of
+// which only ID and StartedAt are set yet. The finished run is recorded
+// under the same ID.
+func (s *Scheduler) RunNow(name string) (Schemas.JobRun, error) {
+	run, err := s.begin(name, TriggerManual)
+	if err != nil {
+		return Schemas.JobRun{}, err
+	}
+
+	s.manual.Add(1)
+	go func() {
+		defer s.manual.Done()
+		s.execute(run)
+	}()
+	return run, nil
+}
+
+// begin marks the job as running here and takes its lease, it returns
+// ErrJobRunning when the job already runs on this or another instance
+func (s *Scheduler) begin(name string, trigger string) (Schemas.JobRun, error) {
+	s.mu.Lock()
+	job, exists := s.jobs[name]
+	if !exists {
+		s.mu.Unlock()
+		return Schemas.JobRun{}, ErrUnknownJob
+	}
+	if job.running {
+		s.mu.Unlock()
+		return Schemas.JobRun{}, ErrJobRunning
+	}
+	job.running = true
+	s.mu.Unlock()
+
+	now := time.Now().UTC()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	acquired, err := Repository.JobLeases().Acquire(ctx, name, s.holder, now, now.Add(jobTimeout+leaseMargin))
+	if err != nil || !acquired {
+		s.finish(job)
+		if err != nil {
+			return Schemas.JobRun{}, fmt.Errorf("could not take lease of job %s: %w", name, err)
+		}
+		return Schemas.JobRun{}, ErrJobRunning
+	}
+
+	return Schemas.JobRun{ID: primitive.NewObjectID(), Job: name, Trigger: trigger, StartedAt: now}, nil
+}
+
+// finish marks job as no longer running here
+func (s *Scheduler) finish(job *registeredJob) {
+	s.mu.Lock()
+	job.running = false
+	s.mu.Unlock()
+}
+
+// execute runs a job started by begin, records the run and gives up the
+// lease
+func (s *Scheduler) execute(run Schemas.JobRun) {
+	s.mu.Lock()
+	job := s.jobs[run.Job]
+	s.mu.Unlock()
+	defer s.finish(job)
+
+	ctx, cancel := context.WithTimeout(context.Background(), jobTimeout)
+	defer cancel()
+
+	name, trigger := run.Job, run.Trigger
+	logger := slog.Default().With("job", name, "job_run_id", run.ID.Hex(), "trigger", trigger)
+	ctx = Logging.WithLogger(ctx, logger)
+
+	defer func() {
+		// The run's context may be spent, releasing gets its own
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := Repository.JobLeases().Release(releaseCtx, name, s.holder); err != nil {
+			logger.Error("Could not release job lease", "error", err)
+		}
+	}()
+
+	result, err := runSafely(ctx, job.Run)
+	run.FinishedAt = time.Now().UTC()
+	run.DurationMs = run.FinishedAt.Sub(run.StartedAt).Milliseconds()
+	run.Success = err == nil
+	run.Result = result
+	if err != nil {
+		run.Error = err.Error()
+		logger.Error("Job failed", "latency_ms", run.DurationMs, "error", err)
+	} else {
+		logger.Info("Job finished", "latency_ms", run.DurationMs, "result", result)
+	}
+
+	Metrics.JobRuns.WithLabelValues(name, trigger, Metrics.Outcome(err)).Inc()
+	Metrics.JobRunDuration.WithLabelValues(name).Observe(run.FinishedAt.Sub(run.StartedAt).Seconds())
+
+	if err := Repository.JobRuns().Record(ctx, run); err != nil {
+		logger.Error("Could not record job run", "error", err)
+	}
+}
+
+// runSafely turns a panic in a job into an error so the scheduler survives
+func runSafely(ctx context.Context, run func(ctx context.Context) (string, error)) (result string, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+	return run(ctx)
+}
+
+// Status lists every registered job with its next and last run
+func (s *Scheduler) Status(ctx context.Context) []JobStatus {
+	s.mu.Lock()
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		status := JobStatus{Name: job.Name, Schedule: job.Schedule, Running: job.running}
+		if job.entryID != 0 {
+			if next := s.cron.Entry(job.entryID).Next; !next.IsZero() {
+				status.NextRun = &next
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	s.mu.Unlock()
+
+	for i := range statuses {
+		if lastRun, err := Repository.JobRuns().FindLatest(ctx, statuses[i].Name); err == nil {
+			statuses[i].LastRun = &lastRun
+		}
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// RegisterDefaultJobs registers every job of this package on Default with
+// the schedules and lock policy of cfg
+func RegisterDefaultJobs(cfg Config.Jobs) error {
+	lockPolicy = LockPolicyFromConfig(cfg.LockOldPosts)
+
+	return Default.Register(Job{
+		Name:     "lock_old_posts",
+		Schedule: cfg.Schedules["lock_old_posts"],
+		Run:      LockOldPosts,
+	})
+}
@@ -0,0 +1,110 @@
+package cronjobs
+
+import (
+	"backend/Repository"
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingJob registers a job on a new scheduler that counts its runs and
+// does not return until release is closed
+func blockingJob(t *testing.T) (scheduler *Scheduler, runs *atomic.Int32, release chan struct{}) {
+	t.Helper()
+	Repository.Use(Repository.NewMemoryRepositories())
+
+	scheduler = NewScheduler()
+	runs = new(atomic.Int32)
+	release = make(chan struct{})
+	err := scheduler.Register(Job{
+		Name:     "block",
+		Schedule: "off",
+		Run: func(ctx context.Context) (string, error) {
+			runs.Add(1)
+			<-release
+			return "done", nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return scheduler, runs, release
+}
+
+// waitForRuns stops scheduler and waits until its runs have finished
+func waitForRuns(t *testing.T, scheduler *Scheduler) {
+	t.Helper()
+	select {
+	case <-scheduler.Stop().Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("runs did not finish")
+	}
+}
+
+func TestSchedulerSkipsOverlappingRun(t *testing.T) {
+	scheduler, runs, release := blockingJob(t)
+
+	if _, err := scheduler.RunNow("block"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scheduler.RunNow("block"); !errors.Is(err, ErrJobRunning) {
+		t.Fatalf("second run err = %v, want ErrJobRunning", err)
+	}
+	if status := scheduler.Status(context.Background()); !status[0].Running {
+		t.Fatal("status does not report the run in progress")
+	}
+
+	close(release)
+	waitForRuns(t, scheduler)
+
+	// The lease was given up, so the job may run again
+	if _, err := scheduler.RunNow("block"); err != nil {
+		t.Fatalf("run after the first finished err = %v", err)
+	}
+	waitForRuns(t, scheduler)
+
+	if got := runs.Load(); got != 2 {
+		t.Fatalf("job ran %d times, want 2", got)
+	}
+}
+
+func TestSchedulerRespectsLeaseOfOtherInstance(t *testing.T) {
+	scheduler, runs, release := blockingJob(t)
+	close(release)
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	acquired, err := Repository.JobLeases().Acquire(ctx, "block", "other-instance", now, now.Add(time.Hour))
+	if err != nil || !acquired {
+		t.Fatalf("Acquire = %v, %v", acquired, err)
+	}
+
+	if _, err := scheduler.RunNow("block"); !errors.Is(err, ErrJobRunning) {
+		t.Fatalf("run while leased err = %v, want ErrJobRunning", err)
+	}
+	if status := scheduler.Status(ctx); status[0].Running {
+		t.Fatal("a skipped run is still reported as running")
+	}
+
+	if err := Repository.JobLeases().Release(ctx, "block", "other-instance"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scheduler.RunNow("block"); err != nil {
+		t.Fatalf("run after release err = %v", err)
+	}
+	waitForRuns(t, scheduler)
+
+	if got := runs.Load(); got != 1 {
+		t.Fatalf("job ran %d times, want 1", got)
+	}
+}
+
+func TestSchedulerUnknownJob(t *testing.T) {
+	scheduler, _, _ := blockingJob(t)
+
+	if _, err := scheduler.RunNow("missing"); !errors.Is(err, ErrUnknownJob) {
+		t.Fatalf("err = %v, want ErrUnknownJob", err)
+	}
+}
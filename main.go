@@ -1,34 +1,192 @@
 package main
 
 import (
+	"backend/Config"
+	"backend/Errors"
+	"backend/Functions"
+	"backend/FunctionsHelper"
 	"backend/HTTP"
+	"backend/Logging"
+	"backend/Mailer"
+	"backend/Metrics"
 	"backend/Mongo"
+	"backend/Repository"
+	cronjobs "backend/cronJobs"
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	// Connect to MongoDB
-	Mongo.ConnectToMongoDB()
+	// Load the configuration once and hand every subsystem its part
+	config, err := Config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fatal("Could not load the configuration", err)
+	}
+	if err := config.AI.Validate(); err != nil {
+		fatal("Could not load the configuration", err)
+	}
+
+	// Everything logs through slog from here on, including the standard log
+	// package used by libraries
+	slog.SetDefault(Logging.New(config.Logging, os.Stderr))
+
+	Repository.Configure(config.Storage)
+	FunctionsHelper.SetAIProvider(FunctionsHelper.NewAIProvider(config.AI))
+	FunctionsHelper.SetJWTSecret(config.Auth.JWTSecret)
+	Mailer.Set(Mailer.New(config.Mail))
+	Functions.Configure(config)
+
+	// Connect to MongoDB. An unreachable server is only logged, /readyz
+	// reports it until it answers.
+	if config.Storage.Backend == "mongo" {
+		Mongo.Configure(config.Storage.MongoURI)
+		if err := Mongo.ConnectToMongoDB(); err != nil {
+			fatal("Could not connect to MongoDB", err)
+		}
+
+		// Likes change the like and its counter in one transaction, which a
+		// standalone server cannot run
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := Mongo.Ping(ctx); err != nil {
+			slog.Warn("MongoDB does not answer yet", "error", err)
+		} else if err := Mongo.CheckTransactions(ctx); errors.Is(err, Mongo.ErrNoTransactions) {
+			fatal("MongoDB cannot run transactions", err)
+		} else if err != nil {
+			slog.Warn("Could not check MongoDB for transactions", "error", err)
+		}
+		cancel()
+	}
+
+	// Create the indexes the repositories rely on (e.g. full-text search)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	if err := Repository.EnsureIndexes(ctx); err != nil {
+		slog.Error("Could not create database indexes", "error", err)
+	}
+	cancel()
 
-	// Create a Gin router
-	router := gin.Default()
+	// Run background jobs on their schedules
+	if err := cronjobs.RegisterDefaultJobs(config.Jobs); err != nil {
+		fatal("Could not register jobs", err)
+	}
+	cronjobs.Default.Start()
+
+	// Create a Gin router. Handlers find the request logger through their
+	// gin.Context, which needs the fallback to the request context. Errors
+	// handlers abort with are answered by Errors.Middleware.
+	router := gin.New()
+	router.ContextWithFallback = true
+	// ClientIP, which per IP rate limits key on, only follows X-Forwarded-For
+	// from these
+	if err := router.SetTrustedProxies(config.HTTP.TrustedProxies); err != nil {
+		fatal("Invalid trusted proxies", err)
+	}
+	router.Use(gin.CustomRecovery(Errors.Recovery), Logging.Middleware, Metrics.Middleware, Errors.Middleware)
 
 	// Configure CORS for the frontend
 	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:5173"}, // Frontend URL
+		AllowOrigins:     config.HTTP.CORSOrigins, // Frontend URLs
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", Logging.RequestIDHeader},
+		ExposeHeaders:    []string{"Content-Length", Logging.RequestIDHeader},
 		AllowCredentials: true,
 	}))
 
 	// Set up HTTP routes
 	HTTP.Router(router)
 
-	// Start the server
-	if err := router.Run("localhost:8080"); err != nil {
-		panic(err)
+	server := &http.Server{
+		Addr:              config.HTTP.Addr,
+		Handler:           router,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Start the server, and the metrics server unless metrics are off
+	serverErr := make(chan error, 2)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+	var metricsServer *http.Server
+	if config.HTTP.MetricsAddr != "" {
+		metricsServer = Metrics.Server(config.HTTP.MetricsAddr)
+		go func() {
+			serverErr <- metricsServer.ListenAndServe()
+		}()
+	}
+	Functions.SetReady(true)
+
+	// Run until SIGINT or SIGTERM, or until the server fails
+	signals, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	exitCode := 0
+	select {
+	case err := <-serverErr:
+		slog.Error("Server stopped", "error", err)
+		exitCode = 1
+	case <-signals.Done():
+		slog.Info("Shutting down")
 	}
+	stop()
+
+	shutdown(server, metricsServer, config.HTTP)
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+}
+
+// shutdown stops everything in order: it reports not ready, stops accepting
+// requests and waits for the running ones, disconnects the chat clients,
+// waits for running jobs, closes the database connection and finally stops
+// serving metrics. All of it shares the configured shutdown timeout.
+func shutdown(server *http.Server, metricsServer *http.Server, config Config.HTTP) {
+	Functions.SetReady(false)
+	time.Sleep(time.Duration(config.DrainDelay))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.ShutdownTimeout))
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Warn("HTTP requests did not finish", "error", err)
+	}
+
+	// WebSocket connections are hijacked, so server.Shutdown does not wait for them
+	if err := Functions.ShutdownChat(ctx); err != nil {
+		slog.Warn("Chat clients did not finish", "error", err)
+	}
+
+	select {
+	case <-cronjobs.Default.Stop().Done():
+	case <-ctx.Done():
+		slog.Warn("Running jobs did not finish", "error", ctx.Err())
+	}
+
+	if err := Mongo.Disconnect(ctx); err != nil {
+		slog.Warn("Could not disconnect from MongoDB", "error", err)
+	}
+
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(ctx); err != nil {
+			slog.Warn("Metrics requests did not finish", "error", err)
+		}
+	}
+	slog.Info("Shutdown complete")
+}
+
+// fatal logs err and exits
+func fatal(message string, err error) {
+	slog.Error(message, "error", err)
+	os.Exit(1)
 }